@@ -0,0 +1,65 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	cmd "github.com/maxgio92/wfind/cmd/find"
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	validate := func(opts ...find.Option) error {
+		o := &find.Options{SeedURLs: []string{"http://localhost/"}, FilenameRegexp: ".+"}
+		for _, f := range opts {
+			f(o)
+		}
+
+		return errors.Wrap(o.Validate(), "error validating Command")
+	}
+
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"success", nil, 0},
+		{"no seeds", validate(find.WithSeedURLs(nil)), cmd.ExitCodeInvalidInput},
+		{"invalid seed", validate(find.WithSeedURLs([]string{"ftp://localhost/"})), cmd.ExitCodeInvalidInput},
+		{"no file name expression", validate(find.WithFilenameRegexp("")), cmd.ExitCodeInvalidInput},
+		{"invalid file name expression", validate(find.WithFilenameRegexp("(")), cmd.ExitCodeInvalidInput},
+		{"unsupported file type", validate(find.WithFileType("l")), cmd.ExitCodeInvalidInput},
+		{"unsupported sort key", validate(find.WithSort("size-desc")), cmd.ExitCodeInvalidInput},
+		{"negative parallelism", validate(find.WithParallelism(-1)), cmd.ExitCodeInvalidInput},
+		{"negative budget", validate(find.WithTimeout(-1)), cmd.ExitCodeInvalidInput},
+		{"fetch error", &find.PartialResultError{Failures: []find.Failure{
+			{URL: &url.URL{Scheme: "http", Host: "localhost", Path: "/"}, StatusCode: http.StatusBadGateway, Attempts: 1},
+		}}, cmd.ExitCodeFetchError},
+		{"other error", fmt.Errorf("error scraping URL"), cmd.ExitCodeError},
+	} {
+		assert.Equal(t, tc.expected, cmd.ExitCode(tc.err), tc.name)
+	}
+}
@@ -3,6 +3,7 @@ module github.com/maxgio92/wfind
 go 1.20
 
 require (
+	github.com/bits-and-blooms/bloom/v3 v3.0.1
 	github.com/cenkalti/backoff v2.2.1+incompatible
 	github.com/gocolly/colly v1.2.0
 	github.com/onsi/ginkgo/v2 v2.11.0
@@ -10,8 +11,12 @@ require (
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v1.6.1
 	github.com/stretchr/testify v1.8.1
+	github.com/temoto/robotstxt v1.1.2
 	github.com/vitorsalgado/mocha/v3 v3.0.2
+	go.etcd.io/bbolt v1.3.7
+	golang.org/x/net v0.10.0
 	golang.org/x/sys v0.9.0
+	golang.org/x/text v0.9.0
 )
 
 require (
@@ -20,6 +25,7 @@ require (
 	github.com/antchfx/htmlquery v1.2.4 // indirect
 	github.com/antchfx/xmlquery v1.3.9 // indirect
 	github.com/antchfx/xpath v1.2.0 // indirect
+	github.com/bits-and-blooms/bitset v1.2.0 // indirect
 	github.com/chzyer/readline v1.5.1 // indirect
 	github.com/cpuguy83/go-md2man/v2 v2.0.2 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -40,9 +46,6 @@ require (
 	github.com/saintfish/chardet v0.0.0-20120816061221-3af4cd4741ca // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/stretchr/objx v0.5.0 // indirect
-	github.com/temoto/robotstxt v1.1.2 // indirect
-	golang.org/x/net v0.10.0 // indirect
-	golang.org/x/text v0.9.0 // indirect
 	golang.org/x/tools v0.9.3 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/protobuf v1.28.0 // indirect
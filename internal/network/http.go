@@ -55,6 +55,7 @@ const (
 )
 
 var DefaultClientTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
 	DialContext: (&net.Dialer{
 		Timeout:   DefaultTimeout * time.Millisecond,
 		KeepAlive: DefaultKeepAlive * time.Millisecond,
@@ -97,9 +98,13 @@ func WithTLSHandshakeTimeout(TLSHandshakeTimeout time.Duration) TransportOption
 	}
 }
 
+// NewTransport returns a new HTTP client transport.
+// By default, it uses the proxy configured with environment variables.
 func NewTransport(opts ...TransportOption) *http.Transport {
 	t := &http.Transport{}
 
+	WithProxyFromEnvironment()(t)
+
 	for _, f := range opts {
 		f(t)
 	}
@@ -0,0 +1,152 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyDirect is the proxy rule value to send requests directly, without proxy.
+const ProxyDirect = "direct"
+
+// ProxyRule represents the proxy to use for requests to the hosts matching a pattern.
+type ProxyRule struct {
+	// Host is the host, or wildcard pattern like *.kernel.org, the rule applies to.
+	Host string
+
+	// URL is the URL of the proxy. If nil, requests are sent directly.
+	URL *url.URL
+}
+
+// ParseProxyURL parses the URL of an HTTP, HTTPS or SOCKS5 proxy.
+// Credentials can be specified as the URL user info.
+func ParseProxyURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		//nolint:goerr113
+		return nil, fmt.Errorf("proxy scheme %q not supported", u.Scheme)
+	}
+
+	if u.Host == "" {
+		//nolint:goerr113
+		return nil, fmt.Errorf("proxy URL %q has no host", s)
+	}
+
+	return u, nil
+}
+
+// ParseProxyRule parses a proxy rule in the form HOST=URL, where URL is either the URL
+// of the proxy or "direct".
+func ParseProxyRule(s string) (*ProxyRule, error) {
+	host, proxy, ok := strings.Cut(s, "=")
+	if !ok || host == "" {
+		//nolint:goerr113
+		return nil, fmt.Errorf("proxy rule %q is not in the form HOST=URL", s)
+	}
+
+	if _, err := path.Match(host, ""); err != nil {
+		return nil, err
+	}
+
+	rule := &ProxyRule{Host: host}
+
+	if proxy == ProxyDirect {
+		return rule, nil
+	}
+
+	u, err := ParseProxyURL(proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.URL = u
+
+	return rule, nil
+}
+
+// matches returns whether the rule applies to the host of the URL u.
+func (r *ProxyRule) matches(u *url.URL) bool {
+	return HostMatches(r.Host, u)
+}
+
+// HostMatches returns whether the host of the URL u, with or without port,
+// matches the case-insensitive wildcard pattern, like *.kernel.org.
+func HostMatches(pattern string, u *url.URL) bool {
+	pattern = strings.ToLower(pattern)
+
+	if ok, _ := path.Match(pattern, strings.ToLower(u.Hostname())); ok {
+		return true
+	}
+
+	ok, _ := path.Match(pattern, strings.ToLower(u.Host))
+
+	return ok
+}
+
+// WithProxyFromEnvironment sets the proxy from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables (or the lowercase versions thereof), as read when the option is applied.
+func WithProxyFromEnvironment() TransportOption {
+	return func(o *http.Transport) {
+		proxy := httpproxy.FromEnvironment().ProxyFunc()
+
+		o.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxy(req.URL)
+		}
+	}
+}
+
+// WithProxyURL sets the proxy for all the requests.
+func WithProxyURL(proxyURL *url.URL) TransportOption {
+	return func(o *http.Transport) {
+		o.Proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+// WithProxyRules sets the proxies for the requests to specific hosts.
+// The first rule matching the host of a request applies; requests to hosts matching
+// no rule use the proxy previously set.
+func WithProxyRules(rules []*ProxyRule) TransportOption {
+	return func(o *http.Transport) {
+		next := o.Proxy
+
+		o.Proxy = func(req *http.Request) (*url.URL, error) {
+			for _, v := range rules {
+				if v.matches(req.URL) {
+					return v.URL, nil
+				}
+			}
+
+			if next == nil {
+				return nil, nil
+			}
+
+			return next(req)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network_test
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxgio92/wfind/internal/network"
+)
+
+const proxiedURL = "http://mirror.wfind.invalid/pub/"
+
+// initProxy returns a stand-in of a forward HTTP proxy, replying with its name
+// and the absolute URL requested.
+func initProxy(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proxy", name)
+		w.Header().Set("X-Proxy-Authorization", r.Header.Get("Proxy-Authorization"))
+		_, _ = io.WriteString(w, r.URL.String())
+	}))
+	t.Cleanup(proxy.Close)
+
+	return proxy
+}
+
+func get(t *testing.T, transport http.RoundTripper, u string) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := (&http.Client{Transport: transport}).Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp, string(body)
+}
+
+func TestWithProxyURL(t *testing.T) {
+	t.Parallel()
+
+	proxy := initProxy(t, "default")
+
+	proxyURL, err := network.ParseProxyURL(proxy.URL)
+	assert.Nil(t, err)
+
+	proxyURL.User = url.UserPassword("user", "secret")
+
+	resp, body := get(t, network.NewTransport(network.WithProxyURL(proxyURL)), proxiedURL)
+
+	assert.Equal(t, "default", resp.Header.Get("X-Proxy"))
+	assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("user:secret")),
+		resp.Header.Get("X-Proxy-Authorization"))
+	assert.Equal(t, proxiedURL, body)
+}
+
+//nolint:paralleltest
+func TestWithProxyFromEnvironment(t *testing.T) {
+	proxy := initProxy(t, "environment")
+
+	t.Setenv("HTTP_PROXY", proxy.URL)
+	t.Setenv("NO_PROXY", "")
+
+	resp, body := get(t, network.NewTransport(), proxiedURL)
+
+	assert.Equal(t, "environment", resp.Header.Get("X-Proxy"))
+	assert.Equal(t, proxiedURL, body)
+
+	t.Setenv("NO_PROXY", "wfind.invalid")
+
+	_, err := (&http.Client{Transport: network.NewTransport()}).Get(proxiedURL)
+	assert.NotNil(t, err)
+}
+
+func TestWithProxyRules(t *testing.T) {
+	t.Parallel()
+
+	defaultProxy := initProxy(t, "default")
+	mirrorProxy := initProxy(t, "mirror")
+
+	defaultProxyURL, _ := url.Parse(defaultProxy.URL)
+
+	rule, err := network.ParseProxyRule("*.wfind.invalid=" + mirrorProxy.URL)
+	assert.Nil(t, err)
+
+	transport := network.NewTransport(
+		network.WithProxyURL(defaultProxyURL),
+		network.WithProxyRules([]*network.ProxyRule{rule}),
+	)
+
+	resp, _ := get(t, transport, proxiedURL)
+	assert.Equal(t, "mirror", resp.Header.Get("X-Proxy"))
+
+	resp, _ = get(t, transport, "http://wfind.invalid/pub/")
+	assert.Equal(t, "default", resp.Header.Get("X-Proxy"))
+}
+
+func TestWithProxyRulesDirect(t *testing.T) {
+	t.Parallel()
+
+	proxy := initProxy(t, "default")
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "origin")
+	}))
+	t.Cleanup(origin.Close)
+
+	rule, err := network.ParseProxyRule("127.0.0.1=" + network.ProxyDirect)
+	assert.Nil(t, err)
+
+	transport := network.NewTransport(
+		network.WithProxyURL(proxyURL),
+		network.WithProxyRules([]*network.ProxyRule{rule}),
+	)
+
+	resp, body := get(t, transport, origin.URL)
+	assert.Empty(t, resp.Header.Get("X-Proxy"))
+	assert.Equal(t, "origin", body)
+}
+
+func TestWithProxyURLSOCKS5(t *testing.T) {
+	t.Parallel()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "origin")
+	}))
+	t.Cleanup(origin.Close)
+
+	proxyURL, err := network.ParseProxyURL("socks5://" + initSOCKS5Proxy(t, origin.Listener.Addr().String()))
+	assert.Nil(t, err)
+
+	_, body := get(t, network.NewTransport(network.WithProxyURL(proxyURL)), proxiedURL)
+	assert.Equal(t, "origin", body)
+}
+
+func TestParseProxyURL(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []string{"ftp://proxy:21", "http://", "proxy:3128"} {
+		_, err := network.ParseProxyURL(v)
+		assert.NotNil(t, err, v)
+	}
+
+	for _, v := range []string{"", "*.kernel.org", "*.kernel.org=ftp://proxy:21"} {
+		_, err := network.ParseProxyRule(v)
+		assert.NotNil(t, err, v)
+	}
+}
+
+// initSOCKS5Proxy returns the address of a stand-in of a SOCKS5 proxy, without
+// authentication, connecting every client to the target address.
+func initSOCKS5Proxy(t *testing.T, target string) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveSOCKS5(conn, target)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func serveSOCKS5(conn net.Conn, target string) {
+	defer conn.Close()
+
+	// Greeting: version, number of methods, methods.
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+
+	if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+		return
+	}
+
+	// No authentication required.
+	if _, err := conn.Write([]byte{5, 0}); err != nil {
+		return
+	}
+
+	// Request: version, command, reserved, address type, address, port.
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+
+	var addrLen int
+
+	switch request[3] {
+	case 1:
+		addrLen = net.IPv4len
+	case 4:
+		addrLen = net.IPv6len
+	case 3:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return
+		}
+
+		addrLen = int(l[0])
+	}
+
+	if _, err := io.ReadFull(conn, make([]byte, addrLen+2)); err != nil {
+		return
+	}
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	host, port, _ := net.SplitHostPort(upstream.LocalAddr().String())
+	p, _ := strconv.Atoi(port)
+
+	reply := append([]byte{5, 0, 0, 1}, net.ParseIP(host).To4()...)
+	reply = binary.BigEndian.AppendUint16(reply, uint16(p))
+
+	if _, err := conn.Write(reply); err != nil {
+		return
+	}
+
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+	}()
+
+	_, _ = io.Copy(conn, upstream)
+}
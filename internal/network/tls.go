@@ -0,0 +1,112 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// KeyLogFileEnv is the environment variable with the path of the file where to log
+// the TLS master secrets, in NSS key log format, to allow external programs to decrypt
+// the TLS connections.
+const KeyLogFileEnv = "SSLKEYLOGFILE"
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion parses a TLS version in the form 1.x.
+func ParseTLSVersion(s string) (uint16, error) {
+	v, ok := tlsVersions[s]
+	if !ok {
+		//nolint:goerr113
+		return 0, fmt.Errorf("TLS version %q not supported", s)
+	}
+
+	return v, nil
+}
+
+// LoadCertPool returns the system certificate pool with the PEM encoded certificates
+// in the file at path appended.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		//nolint:goerr113
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+
+	return pool, nil
+}
+
+func WithRootCAs(pool *x509.CertPool) TransportOption {
+	return func(o *http.Transport) {
+		tlsClientConfig(o).RootCAs = pool
+	}
+}
+
+func WithClientCertificates(certs ...tls.Certificate) TransportOption {
+	return func(o *http.Transport) {
+		tlsClientConfig(o).Certificates = certs
+	}
+}
+
+func WithMinTLSVersion(version uint16) TransportOption {
+	return func(o *http.Transport) {
+		tlsClientConfig(o).MinVersion = version
+	}
+}
+
+// WithInsecureSkipVerify disables the verification of the servers certificate chain and host name.
+func WithInsecureSkipVerify(insecure bool) TransportOption {
+	return func(o *http.Transport) {
+		//nolint:gosec
+		tlsClientConfig(o).InsecureSkipVerify = insecure
+	}
+}
+
+// WithKeyLogWriter sets the destination of the TLS master secrets, in NSS key log format.
+func WithKeyLogWriter(w io.Writer) TransportOption {
+	return func(o *http.Transport) {
+		tlsClientConfig(o).KeyLogWriter = w
+	}
+}
+
+// tlsClientConfig returns the TLS configuration of the transport t, initializing it if needed.
+func tlsClientConfig(t *http.Transport) *tls.Config {
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	return t.TLSClientConfig
+}
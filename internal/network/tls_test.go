@@ -0,0 +1,188 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxgio92/wfind/internal/network"
+)
+
+func initTLSServer(t *testing.T, config *tls.Config) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+	}))
+	server.TLS = config
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// writeCACert writes the certificate of the server in PEM format to a file and returns its path.
+func writeCACert(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// newClientCertificate returns a self-signed client certificate with the common name cn.
+func newClientCertificate(t *testing.T, cn string) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestWithRootCAs(t *testing.T) {
+	t.Parallel()
+
+	server := initTLSServer(t, nil)
+
+	_, err := (&http.Client{Transport: network.NewTransport()}).Get(server.URL)
+	assert.NotNil(t, err)
+
+	pool, err := network.LoadCertPool(writeCACert(t, server))
+	assert.Nil(t, err)
+
+	resp, _ := get(t, network.NewTransport(network.WithRootCAs(pool)), server.URL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestWithClientCertificates(t *testing.T) {
+	t.Parallel()
+
+	server := initTLSServer(t, &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12})
+	pool, _ := network.LoadCertPool(writeCACert(t, server))
+
+	_, err := (&http.Client{Transport: network.NewTransport(network.WithRootCAs(pool))}).Get(server.URL)
+	assert.NotNil(t, err)
+
+	transport := network.NewTransport(
+		network.WithRootCAs(pool),
+		network.WithClientCertificates(newClientCertificate(t, "wfind")),
+	)
+
+	_, body := get(t, transport, server.URL)
+	assert.Equal(t, "wfind", body)
+}
+
+func TestWithMinTLSVersion(t *testing.T) {
+	t.Parallel()
+
+	server := initTLSServer(t, &tls.Config{MaxVersion: tls.VersionTLS12, MinVersion: tls.VersionTLS12})
+	pool, _ := network.LoadCertPool(writeCACert(t, server))
+
+	for version, ok := range map[string]bool{"1.2": true, "1.3": false} {
+		v, err := network.ParseTLSVersion(version)
+		assert.Nil(t, err)
+
+		transport := network.NewTransport(network.WithRootCAs(pool), network.WithMinTLSVersion(v))
+
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if ok {
+			assert.Nil(t, err, version)
+			resp.Body.Close()
+		} else {
+			assert.NotNil(t, err, version)
+		}
+	}
+
+	_, err := network.ParseTLSVersion("1.4")
+	assert.NotNil(t, err)
+}
+
+func TestWithInsecureSkipVerify(t *testing.T) {
+	t.Parallel()
+
+	server := initTLSServer(t, nil)
+
+	resp, _ := get(t, network.NewTransport(network.WithInsecureSkipVerify(true)), server.URL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestWithKeyLogWriter(t *testing.T) {
+	t.Parallel()
+
+	server := initTLSServer(t, nil)
+	pool, _ := network.LoadCertPool(writeCACert(t, server))
+
+	var keyLog bytes.Buffer
+
+	get(t, network.NewTransport(network.WithRootCAs(pool), network.WithKeyLogWriter(&keyLog)), server.URL)
+	assert.NotEmpty(t, keyLog.String())
+}
+
+func TestLoadCertPool(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(path, []byte("no certificates"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := network.LoadCertPool(path)
+	assert.NotNil(t, err)
+
+	_, err = network.LoadCertPool(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.NotNil(t, err)
+}
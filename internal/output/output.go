@@ -22,10 +22,15 @@ import (
 )
 
 func ExitOnErr(err error) {
+	ExitOnErrWithCode(err, 1)
+}
+
+// ExitOnErrWithCode exits with code if err is not nil, and successfully otherwise.
+func ExitOnErrWithCode(err error, code int) {
 	if err != nil {
 		//nolint:forbidigo
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(code)
 	}
 
 	os.Exit(0)
@@ -35,3 +40,7 @@ func Print(s string) {
 	//nolint:forbidigo
 	fmt.Println(s)
 }
+
+func PrintErr(s string) {
+	fmt.Fprintln(os.Stderr, s)
+}
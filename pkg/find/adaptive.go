@@ -0,0 +1,166 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAdaptiveMaxParallelism is the maximum number of concurrent requests to each host
+	// with adaptive concurrency, when not limited by the per host parallelism.
+	DefaultAdaptiveMaxParallelism = 16
+
+	// adaptiveInitialParallelism is the initial number of concurrent requests to each host
+	// with adaptive concurrency.
+	adaptiveInitialParallelism = 2
+
+	// adaptiveLatencyTolerance is the factor of the minimum latency above which
+	// the latency of a host is not healthy.
+	adaptiveLatencyTolerance = 2
+
+	// adaptiveLatencySmoothing is the weight of each latency sample in its moving average.
+	adaptiveLatencySmoothing = 0.2
+)
+
+// aimd limits the concurrent requests to a host with an additive increase, multiplicative
+// decrease algorithm: the limit increases while latency and errors are healthy, and halves
+// on timeouts, connection resets and 429 or 503 status codes.
+type aimd struct {
+	host    string
+	max     float64
+	verbose bool
+
+	mu         sync.Mutex
+	limit      float64
+	inflight   int
+	changed    chan struct{}
+	latency    time.Duration
+	minLatency time.Duration
+	decreased  time.Time
+}
+
+func newAIMD(host string, maxParallelism int, verbose bool) *aimd {
+	if maxParallelism <= 0 {
+		maxParallelism = DefaultAdaptiveMaxParallelism
+	}
+
+	return &aimd{
+		host:    host,
+		max:     float64(maxParallelism),
+		verbose: verbose,
+		limit:   math.Min(adaptiveInitialParallelism, float64(maxParallelism)),
+		changed: make(chan struct{}),
+	}
+}
+
+// acquire waits for the number of concurrent requests to be below the limit.
+func (a *aimd) acquire(ctx context.Context) error {
+	for {
+		a.mu.Lock()
+
+		if a.inflight < int(a.limit) {
+			a.inflight++
+			a.mu.Unlock()
+
+			return nil
+		}
+
+		changed := a.changed
+		a.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (a *aimd) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.inflight--
+	a.notify()
+}
+
+// notify wakes up the requests waiting for the limit. It must be called with the lock held.
+func (a *aimd) notify() {
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// observe adjusts the limit to the outcome of a request.
+func (a *aimd) observe(latency time.Duration, status int, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	before := int(a.limit)
+
+	if congested(status, err) {
+		// Decrease once per round trip, as the requests in flight fail together.
+		if time.Since(a.decreased) < a.latency {
+			return
+		}
+
+		a.limit = math.Max(1, a.limit/2)
+		a.decreased = time.Now()
+	} else if err == nil {
+		if a.latency == 0 {
+			a.latency = latency
+		} else {
+			a.latency = time.Duration(adaptiveLatencySmoothing*float64(latency) +
+				(1-adaptiveLatencySmoothing)*float64(a.latency))
+		}
+
+		if a.minLatency == 0 || latency < a.minLatency {
+			a.minLatency = latency
+		}
+
+		if a.latency <= adaptiveLatencyTolerance*a.minLatency {
+			a.limit = math.Min(a.max, a.limit+1/a.limit)
+		}
+	}
+
+	if after := int(a.limit); after != before {
+		if a.verbose {
+			log.Printf("concurrency for host %s: %d\n", a.host, after)
+		}
+
+		a.notify()
+	}
+}
+
+// congested returns whether the outcome of a request signals the congestion of the host.
+func congested(status int, err error) bool {
+	if err != nil {
+		switch ClassifyError(0, err) {
+		case ErrorClassTimeout, ErrorClassContextDeadline, ErrorClassConnReset:
+			return true
+		default:
+			return false
+		}
+	}
+
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
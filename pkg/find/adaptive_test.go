@@ -0,0 +1,101 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestAIMD(t *testing.T) {
+	t.Parallel()
+
+	a := newAIMD("localhost", 8, false)
+	assert.Equal(t, adaptiveInitialParallelism, int(a.limit))
+
+	// Healthy responses increase the limit up to the maximum.
+	for i := 0; i < 100; i++ {
+		a.observe(time.Millisecond, http.StatusOK, nil)
+	}
+
+	assert.Equal(t, 8, int(a.limit))
+
+	// Unhealthy latency holds the limit.
+	a.limit = 4
+
+	for i := 0; i < 10; i++ {
+		a.observe(100*time.Millisecond, http.StatusOK, nil)
+	}
+
+	assert.Equal(t, 4, int(a.limit))
+
+	// Congestion halves the limit, once per round trip.
+	a.observe(time.Millisecond, http.StatusServiceUnavailable, nil)
+	assert.Equal(t, 2, int(a.limit))
+
+	a.observe(time.Millisecond, http.StatusTooManyRequests, nil)
+	assert.Equal(t, 2, int(a.limit))
+
+	a.decreased = time.Time{}
+	a.observe(time.Millisecond, 0, unix.ECONNRESET)
+	assert.Equal(t, 1, int(a.limit))
+
+	a.decreased = time.Time{}
+	a.limit = 4
+	a.observe(time.Millisecond, 0, context.DeadlineExceeded)
+	assert.Equal(t, 2, int(a.limit))
+
+	// Other errors don't change the limit, even with healthy latency.
+	a.decreased = time.Time{}
+	a.limit = 1
+	a.latency, a.minLatency = time.Millisecond, time.Millisecond
+	a.observe(time.Millisecond, 0, fmt.Errorf("unknown"))
+	assert.Equal(t, 1, int(a.limit))
+
+	// Other error statuses are healthy responses.
+	a.observe(time.Millisecond, http.StatusNotFound, nil)
+	assert.Equal(t, 2, int(a.limit))
+}
+
+func TestAIMDAcquire(t *testing.T) {
+	t.Parallel()
+
+	a := newAIMD("localhost", 2, false)
+
+	assert.Nil(t, a.acquire(context.Background()))
+	assert.Nil(t, a.acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, a.acquire(ctx), context.DeadlineExceeded)
+
+	acquired := make(chan error)
+
+	go func() {
+		acquired <- a.acquire(context.Background())
+	}()
+
+	a.release()
+	assert.Nil(t, <-acquired)
+}
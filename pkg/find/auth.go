@@ -0,0 +1,274 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// NetrcEnv is the environment variable with the path of the .netrc file.
+	NetrcEnv = "NETRC"
+
+	headerAuthorization = "Authorization"
+)
+
+// Credential represents the credentials to authenticate to a host.
+type Credential struct {
+	// Username is the user name for the basic authentication.
+	Username string
+
+	// Password is the password for the basic authentication.
+	Password string
+
+	// Token is the bearer token. If set, the basic authentication credentials are ignored.
+	Token string
+}
+
+// CredentialProvider provides the credentials to authenticate to hosts.
+type CredentialProvider interface {
+	// Credential returns the credential for the host of the URL u, or nil if there is none.
+	Credential(u *url.URL) (*Credential, error)
+}
+
+// StaticCredentials are credentials indexed by host, with or without port.
+type StaticCredentials map[string]*Credential
+
+func (c StaticCredentials) Credential(u *url.URL) (*Credential, error) {
+	for _, v := range []string{u.Host, u.Hostname()} {
+		for host, credential := range c {
+			if strings.EqualFold(host, v) {
+				return credential, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// CredentialChain provides the credential from the first provider having one for a host.
+type CredentialChain []CredentialProvider
+
+func (c CredentialChain) Credential(u *url.URL) (*Credential, error) {
+	for _, v := range c {
+		credential, err := v.Credential(u)
+		if err != nil || credential != nil {
+			return credential, err
+		}
+	}
+
+	return nil, nil
+}
+
+// NetrcCredentials are the credentials of a .netrc file.
+type NetrcCredentials struct {
+	machines StaticCredentials
+	fallback *Credential
+}
+
+// DefaultNetrcPath returns the path of the .netrc file, from the NETRC environment
+// variable or in the user home directory.
+func DefaultNetrcPath() string {
+	if path := os.Getenv(NetrcEnv); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".netrc")
+}
+
+// LoadNetrc parses the .netrc file at path.
+func LoadNetrc(path string) (*NetrcCredentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &NetrcCredentials{machines: StaticCredentials{}}
+
+	var current *Credential
+
+	macro := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		// Skip macro definitions, terminated by an empty line.
+		if macro {
+			macro = len(fields) > 0
+
+			continue
+		}
+
+		for i := 0; i < len(fields); i++ {
+			switch fields[i] {
+			case "macdef":
+				macro = true
+				i = len(fields)
+			case "default":
+				current = &Credential{}
+				n.fallback = current
+			case "machine", "login", "password", "account":
+				if i+1 >= len(fields) {
+					//nolint:goerr113
+					return nil, fmt.Errorf("missing value for %q in %s", fields[i], path)
+				}
+
+				value := fields[i+1]
+
+				switch {
+				case fields[i] == "machine":
+					current = &Credential{}
+					n.machines[value] = current
+				case current == nil:
+					//nolint:goerr113
+					return nil, fmt.Errorf("%q outside of a machine definition in %s", fields[i], path)
+				case fields[i] == "login":
+					current.Username = value
+				case fields[i] == "password":
+					current.Password = value
+				}
+
+				i++
+			}
+		}
+	}
+
+	return n, scanner.Err()
+}
+
+func (n *NetrcCredentials) Credential(u *url.URL) (*Credential, error) {
+	credential, _ := n.machines.Credential(u)
+	if credential != nil {
+		return credential, nil
+	}
+
+	return n.fallback, nil
+}
+
+// CredentialHelper provides the credentials from an executable implementing the get
+// operation of the git credential helper protocol: it receives the protocol and the host
+// on the standard input, and replies with the username and the password, or with
+// the authtype Bearer and the token as credential, on the standard output.
+type CredentialHelper struct {
+	// Command is the path of the executable.
+	Command string
+
+	mu    sync.Mutex
+	cache map[string]*Credential
+}
+
+func NewCredentialHelper(command string) *CredentialHelper {
+	return &CredentialHelper{Command: command, cache: map[string]*Credential{}}
+}
+
+func (h *CredentialHelper) Credential(u *url.URL) (*Credential, error) {
+	key := u.Scheme + "://" + u.Host
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if credential, ok := h.cache[key]; ok {
+		return credential, nil
+	}
+
+	//nolint:gosec
+	cmd := exec.Command(h.Command, "get")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=%s\nhost=%s\n\n", u.Scheme, u.Host))
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "error running the credential helper")
+	}
+
+	attrs := map[string]string{}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if k, v, ok := strings.Cut(line, "="); ok {
+			attrs[k] = v
+		}
+	}
+
+	var credential *Credential
+
+	switch {
+	case strings.EqualFold(attrs["authtype"], "bearer") && attrs["credential"] != "":
+		credential = &Credential{Token: attrs["credential"]}
+	case attrs["username"] != "" || attrs["password"] != "":
+		credential = &Credential{Username: attrs["username"], Password: attrs["password"]}
+	}
+
+	h.cache[key] = credential
+
+	return credential, nil
+}
+
+// authorize sets in header the Authorization for the credentials of the host of the URL u,
+// or the one of the Headers if the host has none and is one of the seed URLs hosts of the scope s,
+// removing any credentials for other hosts.
+func (o *Options) authorize(s *scope, u *url.URL, header http.Header) error {
+	header.Del(headerAuthorization)
+
+	var (
+		credential *Credential
+		err        error
+	)
+
+	if o.Credentials != nil {
+		credential, err = o.Credentials.Credential(u)
+		if err != nil {
+			return err
+		}
+	}
+
+	if credential == nil {
+		if values := o.Headers.Values(headerAuthorization); len(values) > 0 && s.seedHost(u) {
+			header[headerAuthorization] = append([]string{}, values...)
+		}
+
+		return nil
+	}
+
+	if credential.Token != "" {
+		header.Set(headerAuthorization, "Bearer "+credential.Token)
+
+		return nil
+	}
+
+	header.Set(headerAuthorization, "Basic "+
+		base64.StdEncoding.EncodeToString([]byte(credential.Username+":"+credential.Password)))
+
+	return nil
+}
@@ -0,0 +1,185 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vitorsalgado/mocha/v3"
+	"github.com/vitorsalgado/mocha/v3/expect"
+	"github.com/vitorsalgado/mocha/v3/reply"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func basicAuthorization(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+func TestFindFileCredentials(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	netrc := filepath.Join(dir, "netrc")
+	assert.Nil(t, os.WriteFile(netrc,
+		[]byte("machine example.org login nobody password secret\nmachine 127.0.0.1 login netrc password secret\n"), 0o600))
+
+	netrcCredentials, err := find.LoadNetrc(netrc)
+	assert.Nil(t, err)
+
+	helper := filepath.Join(dir, "helper")
+	assert.Nil(t, os.WriteFile(helper,
+		[]byte("#!/bin/sh\ncat > /dev/null\necho authtype=Bearer\necho credential=helper-token\n"), 0o700))
+
+	for _, tc := range []struct {
+		credentials   find.CredentialProvider
+		authorization string
+	}{
+		{find.StaticCredentials{"127.0.0.1": {Username: "static", Password: "secret"}}, basicAuthorization("static", "secret")},
+		{find.StaticCredentials{"127.0.0.1": {Token: "static-token"}}, "Bearer static-token"},
+		{netrcCredentials, basicAuthorization("netrc", "secret")},
+		{find.NewCredentialHelper(helper), "Bearer helper-token"},
+		{
+			find.CredentialChain{find.StaticCredentials{"example.org": {Token: "other"}}, netrcCredentials},
+			basicAuthorization("netrc", "secret"),
+		},
+	} {
+		m := mocha.New(t).CloseOnCleanup(t)
+		m.Start()
+
+		m.AddMocks(
+			mocha.Get(expect.URLPath(listingPath)).
+				Header("Authorization", expect.ToEqual(tc.authorization)).
+				Reply(reply.OK().BodyString(`<a href="protected">protected</a>`)))
+
+		finder := find.NewFind(
+			find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithCredentials(tc.credentials),
+		)
+
+		found, err := finder.Find()
+
+		assert.Nil(t, err)
+		assert.NotNil(t, found)
+		assert.Equal(t, []string{"protected"}, found.BaseNames)
+	}
+}
+
+func TestFindFileCredentialsRedirect(t *testing.T) {
+	t.Parallel()
+
+	mirror := mocha.New(t).CloseOnCleanup(t)
+	mirror.Start()
+
+	mirror.AddMocks(
+		mocha.Get(expect.URLPath("/mirror/")).
+			Header("Authorization", expect.ToBeEmpty()).
+			Reply(reply.OK().BodyString(`<a href="remote">remote</a>`)))
+
+	m := mocha.New(t).CloseOnCleanup(t)
+	m.Start()
+
+	m.AddMocks(
+		mocha.Get(expect.URLPath(listingPath)).
+			Header("Authorization", expect.ToEqual("Bearer token")).
+			Reply(reply.Status(http.StatusFound).Header("Location", localhostURL(mirror)+"/mirror/")))
+
+	finder := find.NewFind(
+		find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+		find.WithFilenameRegexp(`.+`),
+		find.WithFileType(find.FileTypeReg),
+		find.WithRecursive(true),
+		find.WithAllowedDomains([]string{"localhost"}),
+		find.WithCredentials(find.StaticCredentials{"127.0.0.1": {Token: "token"}}),
+	)
+
+	found, err := finder.Find()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, found)
+	assert.Equal(t, []string{"remote"}, found.BaseNames)
+	m.AssertHits(t, 1)
+	mirror.AssertHits(t, 1)
+}
+
+func TestFindFileAuthorizationHeader(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name          string
+		credentials   find.CredentialProvider
+		authorization string
+	}{
+		{"no credentials", nil, "Bearer header"},
+		{"credentials of other hosts", find.StaticCredentials{"example.org": {Token: "other"}}, "Bearer header"},
+		{"credentials", find.StaticCredentials{"127.0.0.1": {Token: "token"}}, "Bearer token"},
+	} {
+		mirror := mocha.New(t).CloseOnCleanup(t)
+		mirror.Start()
+
+		mirror.AddMocks(
+			mocha.Get(expect.URLPath("/mirror/")).
+				Header("Authorization", expect.ToBeEmpty()).
+				Reply(reply.OK().BodyString(`<a href="remote">remote</a>`)),
+			mocha.Get(expect.URLPath("/redirected/")).
+				Header("Authorization", expect.ToBeEmpty()).
+				Reply(reply.OK().BodyString(`<a href="redirected">redirected</a>`)))
+
+		m := mocha.New(t).CloseOnCleanup(t)
+		m.Start()
+
+		m.AddMocks(
+			mocha.Get(expect.URLPath(listingPath)).
+				Header("Authorization", expect.ToEqual(tc.authorization)).
+				Reply(reply.OK().BodyString(fmt.Sprintf(`<a href="local">local</a><a href="%s/mirror/">mirror/</a>`+
+					`<a href="redirect/">redirect/</a>`, localhostURL(mirror)))),
+			mocha.Get(expect.URLPath(listingPath+"redirect/")).
+				Header("Authorization", expect.ToEqual(tc.authorization)).
+				Reply(reply.Status(http.StatusFound).Header("Location", localhostURL(mirror)+"/redirected/")))
+
+		headers := http.Header{}
+		headers.Set("Authorization", "Bearer header")
+
+		found, err := find.NewFind(
+			find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithFollowExternalLinks(true),
+			find.WithAllowedDomains([]string{"localhost"}),
+			find.WithHeaders(headers),
+			find.WithCredentials(tc.credentials),
+		).Find()
+
+		// The Authorization header is sent to the seed host only, unless overridden by its credentials.
+		assert.Nil(t, err, tc.name)
+		assert.NotNil(t, found, tc.name)
+		assert.ElementsMatch(t, []string{"local", "remote", "redirected"}, found.BaseNames, tc.name)
+		m.AssertHits(t, 2)
+		mirror.AssertHits(t, 2)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly"
+	xhtml "golang.org/x/net/html"
+)
+
+// truncatedHeader is the header marking the responses whose body has been truncated,
+// with the limit in bytes it has been truncated at.
+const truncatedHeader = "X-Wfind-Truncated"
+
+// bodyTransport limits the bodies of the responses, marking the truncated ones.
+// If stream is enabled, the HTML listings are parsed while they're read, keeping only
+// their links, so that they're limited by streamLimit instead of limit.
+type bodyTransport struct {
+	limit       int64
+	stream      bool
+	streamLimit int64
+	next        http.RoundTripper
+}
+
+func (t *bodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if t.stream && streamable(resp) {
+		resp.Body = newLinkReader(newTruncatedReader(resp.Body, t.streamLimit, resp.Header))
+		resp.ContentLength = -1
+		resp.Header.Del("Content-Length")
+
+		return resp, nil
+	}
+
+	if t.limit > 0 {
+		resp.Body = newTruncatedReader(resp.Body, t.limit, resp.Header)
+	}
+
+	return resp, nil
+}
+
+// streamable returns whether the body of resp is an uncompressed HTML document.
+func streamable(resp *http.Response) bool {
+	encoding := resp.Header.Get("Content-Encoding")
+
+	return strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "html") &&
+		(encoding == "" || strings.EqualFold(encoding, "identity"))
+}
+
+// truncatedReader reads up to limit bytes, marking header with truncatedHeader
+// if the body is longer.
+type truncatedReader struct {
+	io.ReadCloser
+	limit     int64
+	remaining int64
+	header    http.Header
+}
+
+func newTruncatedReader(rc io.ReadCloser, limit int64, header http.Header) *truncatedReader {
+	return &truncatedReader{ReadCloser: rc, limit: limit, remaining: limit, header: header}
+}
+
+func (r *truncatedReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		// Check whether the body continues past the limit.
+		var b [1]byte
+		if n, _ := io.ReadFull(r.ReadCloser, b[:]); n > 0 {
+			r.header.Set(truncatedHeader, strconv.FormatInt(r.limit, 10))
+		}
+
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	r.remaining -= int64(n)
+
+	return n, err
+}
+
+// linkReader parses an HTML document while it's read, returning a document made of its links
+// only, along with its meta tags, that may declare its charset.
+type linkReader struct {
+	io.Closer
+	tokenizer *xhtml.Tokenizer
+	buf       bytes.Buffer
+	err       error
+}
+
+func newLinkReader(rc io.ReadCloser) *linkReader {
+	return &linkReader{Closer: rc, tokenizer: xhtml.NewTokenizer(rc)}
+}
+
+func (r *linkReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 && r.err == nil {
+		r.next()
+	}
+
+	if r.buf.Len() > 0 {
+		return r.buf.Read(p)
+	}
+
+	return 0, r.err
+}
+
+// next parses the next token, buffering it if it's a link or a meta tag.
+func (r *linkReader) next() {
+	switch r.tokenizer.Next() {
+	case xhtml.ErrorToken:
+		r.err = r.tokenizer.Err()
+	case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+		name, hasAttr := r.tokenizer.TagName()
+
+		switch string(name) {
+		case "meta":
+			r.buf.Write(r.tokenizer.Raw())
+		case "a":
+			for hasAttr {
+				var key, value []byte
+
+				key, value, hasAttr = r.tokenizer.TagAttr()
+				if string(key) == HTMLAttrRef {
+					fmt.Fprintf(&r.buf, "<a href=\"%s\"></a>\n", html.EscapeString(string(value)))
+
+					break
+				}
+			}
+		}
+	}
+}
+
+// truncationHandler returns the handler of the responses whose body has been truncated,
+// warning about them and adding them to the failures of the results, as the entries
+// past the limit are missing.
+func (o *Options) truncationHandler(results *resultSet) func(*colly.Response) {
+	return func(response *colly.Response) {
+		limit := response.Headers.Get(truncatedHeader)
+		if limit == "" {
+			return
+		}
+
+		response.Headers.Del(truncatedHeader)
+
+		err := fmt.Errorf("%w at %s bytes", ErrBodyTruncated, limit)
+
+		log.Printf("warning: %s: %v\n", response.Request.URL, err)
+
+		results.fail(newFailure(response, err))
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func TestFindFileTruncatedListing(t *testing.T) {
+	t.Parallel()
+
+	const entries = 5000
+
+	// Generate a listing larger than the maximum body size.
+	var listing strings.Builder
+
+	listing.WriteString(`<html><head><meta charset="utf-8"><title>Index</title></head><body><pre>`)
+
+	for i := 0; i < entries; i++ {
+		fmt.Fprintf(&listing, "<a href=\"file-%05d.tar.gz\">file-%05d.tar.gz</a>    01-Jan-2023 00:00    1K\n", i, i)
+	}
+
+	listing.WriteString(`</pre></body></html>`)
+
+	for _, tc := range []struct {
+		name      string
+		options   []find.Option
+		truncated bool
+	}{
+		{"max body size", []find.Option{find.WithMaxBodySize(64 * 1024)}, true},
+		{"stream", []find.Option{find.WithMaxBodySize(64 * 1024), find.WithStreamListings(true)}, false},
+		{"stream max listing size", []find.Option{
+			find.WithStreamListings(true), find.WithMaxListingSize(64 * 1024),
+		}, true},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				fmt.Fprint(w, listing.String())
+			}))
+			defer server.Close()
+
+			finder := find.NewFind(append([]find.Option{
+				find.WithSeedURLs([]string{server.URL + listingPath}),
+				find.WithFilenameRegexp(`.+\.tar\.gz`),
+				find.WithFileType(find.FileTypeReg),
+				find.WithRecursive(false),
+			}, tc.options...)...)
+
+			found, err := finder.Find()
+
+			assert.NotNil(t, found)
+
+			if !tc.truncated {
+				assert.Nil(t, err)
+				assert.Len(t, found.BaseNames, entries)
+				assert.Contains(t, found.BaseNames, fmt.Sprintf("file-%05d.tar.gz", entries-1))
+
+				return
+			}
+
+			var partial *find.PartialResultError
+
+			assert.True(t, errors.As(err, &partial))
+			assert.True(t, errors.Is(err, find.ErrBodyTruncated))
+			assert.Less(t, len(found.BaseNames), entries)
+			assert.NotEmpty(t, found.BaseNames)
+
+			if assert.Len(t, found.Failures, 1) {
+				assert.Equal(t, find.ErrorClassTruncated, found.Failures[0].Class)
+				assert.Equal(t, server.URL+listingPath, found.Failures[0].URL.String())
+			}
+		})
+	}
+}
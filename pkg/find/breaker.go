@@ -0,0 +1,192 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCircuitBreakerCooldown is the default time a circuit breaker stays open.
+const DefaultCircuitBreakerCooldown = 30 * time.Second
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// hostCircuit is the circuit breaker of a host.
+type hostCircuit struct {
+	state    circuitState
+	failures int
+	opened   time.Time
+	probing  bool
+}
+
+// breaker is a circuit breaker for each host: after a number of consecutive failures
+// of the requests to a host it opens, failing fast the requests to the host.
+// After a cool-down it half-opens, letting a request probe the host: if it succeeds,
+// the breaker closes, otherwise it opens again.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	verbose   bool
+
+	mu    sync.Mutex
+	hosts map[string]*hostCircuit
+}
+
+func newBreaker(threshold int, cooldown time.Duration, verbose bool) *breaker {
+	if cooldown <= 0 {
+		cooldown = DefaultCircuitBreakerCooldown
+	}
+
+	return &breaker{threshold: threshold, cooldown: cooldown, verbose: verbose, hosts: map[string]*hostCircuit{}}
+}
+
+func (b *breaker) circuit(host string) *hostCircuit {
+	c, ok := b.hosts[host]
+	if !ok {
+		c = &hostCircuit{}
+		b.hosts[host] = c
+	}
+
+	return c
+}
+
+// setState sets the state of the circuit breaker of a host. It must be called with the lock held.
+func (b *breaker) setState(host string, c *hostCircuit, state circuitState) {
+	if c.state == state {
+		return
+	}
+
+	c.state = state
+
+	if b.verbose {
+		log.Printf("circuit breaker for host %s: %s\n", host, state)
+	}
+}
+
+// allow returns an error wrapping ErrCircuitOpen if the request to the host must fail fast.
+func (b *breaker) allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(host)
+
+	switch c.state {
+	case circuitOpen:
+		if time.Since(c.opened) < b.cooldown {
+			return fmt.Errorf("%w for host %s", ErrCircuitOpen, host)
+		}
+
+		b.setState(host, c, circuitHalfOpen)
+		c.probing = true
+
+		return nil
+	case circuitHalfOpen:
+		if c.probing {
+			return fmt.Errorf("%w for host %s", ErrCircuitOpen, host)
+		}
+
+		c.probing = true
+
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record records the outcome of a request to the host.
+func (b *breaker) record(host string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(host)
+	c.probing = false
+
+	if !failed {
+		c.failures = 0
+		b.setState(host, c, circuitClosed)
+
+		return
+	}
+
+	c.failures++
+
+	if c.state == circuitHalfOpen || c.failures >= b.threshold {
+		c.opened = time.Now()
+		b.setState(host, c, circuitOpen)
+	}
+}
+
+// cancel records a canceled request to the host, leaving the state of its circuit breaker unchanged,
+// so that another request can probe the host if the canceled one was probing it.
+func (b *breaker) cancel(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.circuit(host).probing = false
+}
+
+// breakerTransport is an HTTP transport failing fast the requests to the hosts
+// whose circuit breaker is open.
+type breakerTransport struct {
+	breaker *breaker
+	next    http.RoundTripper
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Host)
+
+	if err := t.breaker.allow(host); err != nil {
+		return nil, err
+	}
+
+	res, err := t.next.RoundTrip(req)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		t.breaker.cancel(host)
+	case err != nil:
+		t.breaker.record(host, true)
+	default:
+		t.breaker.record(host, res.StatusCode >= http.StatusInternalServerError)
+	}
+
+	return res, err
+}
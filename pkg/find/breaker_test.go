@@ -0,0 +1,151 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	t.Parallel()
+
+	b := newBreaker(2, 20*time.Millisecond, false)
+
+	assert.Nil(t, b.allow("localhost"))
+	b.record("localhost", true)
+	assert.Nil(t, b.allow("localhost"))
+	b.record("localhost", false)
+
+	// Failures must be consecutive.
+	b.record("localhost", true)
+	assert.Nil(t, b.allow("localhost"))
+	b.record("localhost", true)
+
+	// Open.
+	assert.True(t, errors.Is(b.allow("localhost"), ErrCircuitOpen))
+	assert.Nil(t, b.allow("example.org"))
+
+	// Half-open lets one probe.
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, b.allow("localhost"))
+	assert.True(t, errors.Is(b.allow("localhost"), ErrCircuitOpen))
+
+	// A failed probe opens again.
+	b.record("localhost", true)
+	assert.True(t, errors.Is(b.allow("localhost"), ErrCircuitOpen))
+
+	// A successful probe closes.
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, b.allow("localhost"))
+	b.record("localhost", false)
+	assert.Nil(t, b.allow("localhost"))
+	assert.Nil(t, b.allow("localhost"))
+}
+
+func TestBreakerTransportCanceled(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	b := newBreaker(2, 20*time.Millisecond, false)
+	transport := &breakerTransport{breaker: b, next: http.DefaultTransport}
+
+	get := func(ctx context.Context) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+
+		res, err := transport.RoundTrip(req)
+		if err == nil {
+			res.Body.Close()
+		}
+
+		return err
+	}
+
+	// Open.
+	assert.Nil(t, get(context.Background()))
+	assert.Nil(t, get(context.Background()))
+	assert.True(t, errors.Is(get(context.Background()), ErrCircuitOpen))
+
+	// A canceled probe leaves the circuit breaker half-open, letting another probe.
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.True(t, errors.Is(get(ctx), context.Canceled))
+	assert.Nil(t, get(context.Background()))
+
+	// The failed probe opens again, rather than counting as the first failure of a closed one.
+	assert.True(t, errors.Is(get(context.Background()), ErrCircuitOpen))
+}
+
+func TestFindFileCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	var hits int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+
+		if r.URL.Path != "/pub/linux/" {
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(w, `<a href="d%d/">d%d/</a>`, i, i)
+		}
+	}))
+	defer server.Close()
+
+	found, err := NewFind(
+		WithSeedURLs([]string{server.URL + "/pub/linux/"}),
+		WithFilenameRegexp(`.+`),
+		WithFileType(FileTypeReg),
+		WithRecursive(true),
+		WithAsync(true),
+		WithPerHostParallelism(1),
+		WithCircuitBreaker(3, time.Minute),
+	).Find()
+
+	var partial *PartialResultError
+
+	assert.ErrorAs(t, err, &partial)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Len(t, found.Failures, 10)
+	assert.Equal(t, int64(1+3), atomic.LoadInt64(&hits))
+
+	classes := map[ErrorClass]int{}
+	for _, v := range found.Failures {
+		classes[v.Class]++
+	}
+
+	assert.Equal(t, map[ErrorClass]int{ErrorClassStatus: 3, ErrorClassCircuitOpen: 7}, classes)
+}
@@ -0,0 +1,186 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// TruncateReason is the reason why the Find job stopped before examining the whole hierarchy.
+type TruncateReason string
+
+const (
+	TruncateMaxRequests TruncateReason = "max-requests"
+	TruncateMaxBytes    TruncateReason = "max-bytes"
+	TruncateMaxResults  TruncateReason = "max-results"
+	TruncateTimeout     TruncateReason = "timeout"
+)
+
+// crawlBudget enforces the global budgets of a Find job: once one of them is exhausted,
+// no more requests are made and the ones in flight are canceled.
+type crawlBudget struct {
+	maxRequests int64
+	maxBytes    int64
+
+	requests int64
+	bytes    int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	reason TruncateReason
+}
+
+func (o *Options) newCrawlBudget() *crawlBudget {
+	b := &crawlBudget{
+		maxRequests: int64(o.MaxRequests),
+		maxBytes:    o.MaxBytes,
+	}
+
+	if o.Timeout > 0 {
+		b.ctx, b.cancel = context.WithTimeout(context.Background(), o.Timeout)
+	} else {
+		b.ctx, b.cancel = context.WithCancel(context.Background())
+	}
+
+	return b
+}
+
+// stop stops the Find job for the reason, unless already stopped.
+func (b *crawlBudget) stop(reason TruncateReason) {
+	b.mu.Lock()
+	if b.reason == "" {
+		b.reason = reason
+	}
+	b.mu.Unlock()
+
+	b.cancel()
+}
+
+// exhausted returns whether the Find job has been stopped.
+func (b *crawlBudget) exhausted() bool {
+	if errors.Is(b.ctx.Err(), context.DeadlineExceeded) {
+		b.stop(TruncateTimeout)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.reason != ""
+}
+
+// truncated returns the reason why the Find job has been stopped, if it has been.
+func (b *crawlBudget) truncated() TruncateReason {
+	b.exhausted()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.reason
+}
+
+// close releases the resources of the budget, once the Find job is finished.
+func (b *crawlBudget) close() {
+	b.cancel()
+}
+
+// request accounts a request, aborting it and returning false if the budget is exhausted.
+func (b *crawlBudget) request(r *colly.Request) bool {
+	if b.exhausted() {
+		r.Abort()
+
+		return false
+	}
+
+	if b.maxRequests > 0 && atomic.AddInt64(&b.requests, 1) > b.maxRequests {
+		b.stop(TruncateMaxRequests)
+		r.Abort()
+
+		return false
+	}
+
+	return true
+}
+
+// response accounts the body of a response. The response exceeding the budget is still examined.
+func (b *crawlBudget) response(r *colly.Response) {
+	if b.maxBytes > 0 && atomic.AddInt64(&b.bytes, int64(len(r.Body))) >= b.maxBytes {
+		b.stop(TruncateMaxBytes)
+	}
+}
+
+// sleep waits for d, returning false if the budget is exhausted meanwhile.
+func (b *crawlBudget) sleep(d time.Duration) bool {
+	return sleep(b.ctx, d) == nil
+}
+
+// transport makes the requests of next canceled once the budget is exhausted.
+func (b *crawlBudget) transport(next http.RoundTripper) http.RoundTripper {
+	return &budgetTransport{ctx: b.ctx, next: next}
+}
+
+type budgetTransport struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+// RoundTrip makes the request with a context canceled either with the one of the request,
+// or once the budget is exhausted, until its response body is closed.
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+
+	go func() {
+		select {
+		case <-t.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// cancelBody is a response body canceling the context of its request once closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
@@ -0,0 +1,148 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func TestFindFileBudgets(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		option   find.Option
+		reason   find.TruncateReason
+		expected []string
+	}{
+		{"none", find.WithMaxRequests(10), "", []string{"a", "b", "c", "x", "y", "slow"}},
+		{"max requests", find.WithMaxRequests(1), find.TruncateMaxRequests, []string{"a", "b", "c"}},
+		{"max bytes", find.WithMaxBytes(1), find.TruncateMaxBytes, []string{"a", "b", "c"}},
+		{"max results", find.WithMaxResults(2), find.TruncateMaxResults, []string{"a", "b"}},
+		{"timeout", find.WithTimeout(300 * time.Millisecond), find.TruncateTimeout, []string{"a", "b", "c", "x", "y"}},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			delay := time.Duration(0)
+			if tc.reason == find.TruncateTimeout {
+				delay = 5 * time.Second
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc(listingPath, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `<a href="a">a</a><a href="b">b</a><a href="c">c</a>`+
+					`<a href="x/">x/</a><a href="y/">y/</a><a href="slow/">slow/</a>`)
+			})
+			for _, v := range []string{"x", "y"} {
+				v := v
+				mux.HandleFunc(listingPath+v+"/", func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprintf(w, `<a href="%s">%s</a>`, v, v)
+				})
+			}
+			mux.HandleFunc(listingPath+"slow/", func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(delay):
+				case <-r.Context().Done():
+					return
+				}
+				fmt.Fprint(w, `<a href="slow">slow</a>`)
+			})
+
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			finder := find.NewFind(
+				find.WithSeedURLs([]string{server.URL + listingPath}),
+				find.WithFilenameRegexp(`.+`),
+				find.WithFileType(find.FileTypeReg),
+				find.WithRecursive(true),
+				find.WithAsync(true),
+				tc.option,
+			)
+
+			start := time.Now()
+			found, err := finder.Find()
+
+			assert.Nil(t, err)
+			assert.NotNil(t, found)
+			assert.Empty(t, found.Failures)
+			assert.ElementsMatch(t, tc.expected, found.BaseNames)
+			assert.Equal(t, tc.reason != "", found.Truncated)
+			assert.Equal(t, tc.reason, found.TruncateReason)
+			assert.Less(t, time.Since(start), time.Second)
+		})
+	}
+}
+
+func TestFindFileBudgetsAborted(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(listingPath, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="denied/">denied/</a><a href="allowed/">allowed/</a>`)
+	})
+	mux.HandleFunc(listingPath+"allowed/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="allowed">allowed</a>`)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The requests aborted, as their credentials can't be got, are not accounted.
+	credentials := credentialsFunc(func(u *url.URL) (*find.Credential, error) {
+		if strings.Contains(u.Path, "denied") {
+			return nil, fmt.Errorf("no credentials for %s", u)
+		}
+
+		return nil, nil
+	})
+
+	found, err := find.NewFind(
+		find.WithSeedURLs([]string{server.URL + listingPath}),
+		find.WithFilenameRegexp(`.+`),
+		find.WithFileType(find.FileTypeReg),
+		find.WithRecursive(true),
+		find.WithAsync(true),
+		find.WithCredentials(credentials),
+		find.WithMaxRequests(2),
+	).Find()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, found)
+	assert.False(t, found.Truncated)
+	assert.Equal(t, []string{"allowed"}, found.BaseNames)
+}
+
+// credentialsFunc is an adapter to use ordinary functions as find.CredentialProvider.
+type credentialsFunc func(u *url.URL) (*find.Credential, error)
+
+func (f credentialsFunc) Credential(u *url.URL) (*find.Credential, error) {
+	return f(u)
+}
@@ -0,0 +1,484 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CacheStatusHeader is the header of the responses served by a Cache, reporting how they're served.
+const CacheStatusHeader = "X-Wfind-Cache"
+
+// CacheStatus is how a response is served by a Cache.
+type CacheStatus string
+
+const (
+	// CacheHit is the status of the responses served from the cache, as still fresh.
+	CacheHit CacheStatus = "hit"
+
+	// CacheRevalidated is the status of the responses served from the cache, after the server
+	// replied 304 Not Modified to the conditional request.
+	CacheRevalidated CacheStatus = "revalidated"
+
+	// CacheStale is the status of the responses served from the cache, after failing to revalidate them.
+	CacheStale CacheStatus = "stale"
+
+	// CacheMiss is the status of the responses served by the server.
+	CacheMiss CacheStatus = "miss"
+)
+
+// CacheStats are the statistics of a Cache.
+type CacheStats struct {
+	Hits        int64
+	Revalidated int64
+	Stale       int64
+	Misses      int64
+	Stores      int64
+}
+
+func (s CacheStats) String() string {
+	return fmt.Sprintf("%d hits, %d revalidated, %d stale, %d misses, %d stored",
+		s.Hits, s.Revalidated, s.Stale, s.Misses, s.Stores)
+}
+
+// Cache is an HTTP transport caching on disk the responses to GET requests, and revalidating
+// them with If-None-Match and If-Modified-Since requests, based on their ETag and Last-Modified headers.
+// The requests with credentials and the private responses are not cached, and the responses
+// varying by request headers are cached for each value of those headers.
+type Cache struct {
+	// Dir is the directory storing the responses.
+	Dir string
+
+	// MaxAge is the time the responses are served without revalidation. If zero, it's the one
+	// stated by their Cache-Control max-age directive or Expires header, if any.
+	MaxAge time.Duration
+
+	// MaxStale is the time past their freshness the responses are served if the revalidation fails,
+	// because of a network error or a 5xx status code.
+	MaxStale time.Duration
+
+	// Transport makes the requests not served from the cache. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	hits        int64
+	revalidated int64
+	stale       int64
+	misses      int64
+	stores      int64
+}
+
+// NewCache returns a Cache storing the responses in dir, making the requests with next.
+func NewCache(dir string, next http.RoundTripper) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.Wrap(err, "error creating the cache directory")
+	}
+
+	return &Cache{Dir: dir, Transport: next}, nil
+}
+
+// Stats returns the statistics of the cache.
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:        atomic.LoadInt64(&c.hits),
+		Revalidated: atomic.LoadInt64(&c.revalidated),
+		Stale:       atomic.LoadInt64(&c.stale),
+		Misses:      atomic.LoadInt64(&c.misses),
+		Stores:      atomic.LoadInt64(&c.stores),
+	}
+}
+
+//nolint:cyclop
+func (c *Cache) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := c.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if !cacheable(req) {
+		return next.RoundTrip(req)
+	}
+
+	path := c.path(req, c.vary(req))
+
+	cached, age, err := c.load(path, req)
+	if err != nil {
+		atomic.AddInt64(&c.misses, 1)
+
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.store(req, resp, CacheMiss), nil
+	}
+
+	lifetime := c.lifetime(cached.Header)
+	if age < lifetime {
+		atomic.AddInt64(&c.hits, 1)
+		cached.Header.Set(CacheStatusHeader, string(CacheHit))
+
+		return cached, nil
+	}
+
+	// Revalidate the cached response.
+	conditional := req.Clone(req.Context())
+	if etag := cached.Header.Get("ETag"); etag != "" {
+		conditional.Header.Set("If-None-Match", etag)
+	}
+
+	if modified := cached.Header.Get("Last-Modified"); modified != "" {
+		conditional.Header.Set("If-Modified-Since", modified)
+	}
+
+	resp, err := next.RoundTrip(conditional)
+
+	switch {
+	case (err != nil || resp.StatusCode >= http.StatusInternalServerError) && age < lifetime+c.MaxStale:
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		atomic.AddInt64(&c.stale, 1)
+		cached.Header.Set(CacheStatusHeader, string(CacheStale))
+
+		return cached, nil
+	case err != nil:
+		cached.Body.Close()
+
+		return nil, err
+	case resp.StatusCode == http.StatusNotModified:
+		resp.Body.Close()
+
+		atomic.AddInt64(&c.revalidated, 1)
+
+		// Refresh the cached response with the headers of the 304 response.
+		for _, v := range []string{"Cache-Control", "Date", "ETag", "Expires", "Last-Modified"} {
+			if value := resp.Header.Get(v); value != "" {
+				cached.Header.Set(v, value)
+			}
+		}
+
+		return c.store(req, cached, CacheRevalidated), nil
+	default:
+		cached.Body.Close()
+
+		atomic.AddInt64(&c.misses, 1)
+
+		return c.store(req, resp, CacheMiss), nil
+	}
+}
+
+// cacheable returns whether the response to req can be served from, and stored in, the cache.
+// The requests with credentials are not, so that a response is never served to another user.
+func cacheable(req *http.Request) bool {
+	return req.Method == http.MethodGet && req.Header.Get("Range") == "" && req.URL.User == nil &&
+		req.Header.Get("Authorization") == "" && req.Header.Get("Cookie") == ""
+}
+
+// path returns the path of the file storing the response to req, varying by the request headers vary.
+func (c *Cache) path(req *http.Request, vary []string) string {
+	h := sha256.New()
+	//nolint:errcheck
+	io.WriteString(h, req.URL.String())
+
+	for _, v := range vary {
+		fmt.Fprintf(h, "\n%s: %s", v, strings.Join(req.Header.Values(v), ", "))
+	}
+
+	return filepath.Join(c.Dir, hex.EncodeToString(h.Sum(nil)))
+}
+
+// varyPath returns the path of the file storing the request headers the response to req varies by.
+func (c *Cache) varyPath(req *http.Request) string {
+	return c.path(req, nil) + ".vary"
+}
+
+// vary returns the request headers the cached response to req varies by, if any.
+func (c *Cache) vary(req *http.Request) []string {
+	data, err := os.ReadFile(c.varyPath(req))
+	if err != nil {
+		return nil
+	}
+
+	return strings.Fields(string(data))
+}
+
+// saveVary saves the request headers vary the response to req varies by.
+func (c *Cache) saveVary(req *http.Request, vary []string) error {
+	path := c.varyPath(req)
+
+	if len(vary) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	f, err := os.CreateTemp(c.Dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(f, strings.Join(vary, "\n"))
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
+
+// varyHeaders returns the request headers the response with header varies by, sorted.
+func varyHeaders(header http.Header) []string {
+	vary := []string{}
+
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				vary = append(vary, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	sort.Strings(vary)
+
+	return vary
+}
+
+// cacheDirectives returns the directives of the Cache-Control header of header, lower-cased.
+func cacheDirectives(header http.Header) []string {
+	directives := []string{}
+
+	for _, v := range strings.Split(header.Get("Cache-Control"), ",") {
+		if v = strings.TrimSpace(strings.ToLower(v)); v != "" {
+			directives = append(directives, v)
+		}
+	}
+
+	return directives
+}
+
+// storable returns whether the response with status code and header can be stored in the cache.
+// The responses not to be stored, private to a user or varying by any request are not.
+func storable(code int, header http.Header) bool {
+	if code != http.StatusOK {
+		return false
+	}
+
+	for _, v := range cacheDirectives(header) {
+		if v == "no-store" || v == "private" || strings.HasPrefix(v, "private=") {
+			return false
+		}
+	}
+
+	for _, v := range varyHeaders(header) {
+		if v == "*" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// load returns the cached response to req stored at path, along with its age.
+func (c *Cache) load(path string, req *http.Request) (*http.Response, time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+
+		return nil, 0, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(f), req)
+	if err != nil {
+		f.Close()
+
+		return nil, 0, err
+	}
+
+	resp.Body = &readCloser{Reader: resp.Body, Closer: f}
+
+	return resp, time.Since(info.ModTime()), nil
+}
+
+// store returns resp to req, marked with the status, storing it while its body is read.
+// Only the successful responses allowed to be stored are stored.
+func (c *Cache) store(req *http.Request, resp *http.Response, status CacheStatus) *http.Response {
+	resp.Header.Set(CacheStatusHeader, string(status))
+
+	if !storable(resp.StatusCode, resp.Header) {
+		return resp
+	}
+
+	vary := varyHeaders(resp.Header)
+	if err := c.saveVary(req, vary); err != nil {
+		return resp
+	}
+
+	path := c.path(req, vary)
+
+	f, err := os.CreateTemp(c.Dir, ".tmp-")
+	if err != nil {
+		return resp
+	}
+
+	header := resp.Header.Clone()
+	header.Del(CacheStatusHeader)
+
+	// The body is read until EOF.
+	header.Del("Content-Length")
+
+	_, err = fmt.Fprintf(f, "HTTP/1.1 %d %s\r\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+	if err == nil {
+		err = header.Write(f)
+	}
+
+	if err == nil {
+		_, err = io.WriteString(f, "\r\n")
+	}
+
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+
+		return resp
+	}
+
+	resp.Body = &cachingBody{ReadCloser: resp.Body, file: f, path: path, cache: c}
+
+	return resp
+}
+
+// lifetime returns the time the response with header is fresh.
+func (c *Cache) lifetime(header http.Header) time.Duration {
+	if c.MaxAge > 0 {
+		return c.MaxAge
+	}
+
+	for _, v := range cacheDirectives(header) {
+		if v == "no-cache" {
+			return 0
+		}
+
+		if seconds, ok := strings.CutPrefix(v, "max-age="); ok {
+			if n, err := strconv.Atoi(seconds); err == nil {
+				return time.Duration(n) * time.Second
+			}
+		}
+	}
+
+	if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			return 0
+		}
+
+		return expires.Sub(date)
+	}
+
+	return 0
+}
+
+// cachingBody is a response body stored in the cache once read until EOF.
+type cachingBody struct {
+	io.ReadCloser
+	file  *os.File
+	path  string
+	cache *Cache
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	if b.file != nil && n > 0 {
+		if _, werr := b.file.Write(p[:n]); werr != nil {
+			b.discard()
+		}
+	}
+
+	if b.file != nil && errors.Is(err, io.EOF) {
+		b.commit()
+	}
+
+	return n, err
+}
+
+func (b *cachingBody) Close() error {
+	if b.file != nil {
+		b.discard()
+	}
+
+	return b.ReadCloser.Close()
+}
+
+func (b *cachingBody) commit() {
+	name := b.file.Name()
+
+	err := b.file.Close()
+	b.file = nil
+
+	if err == nil {
+		err = os.Rename(name, b.path)
+	}
+
+	if err != nil {
+		os.Remove(name)
+
+		return
+	}
+
+	atomic.AddInt64(&b.cache.stores, 1)
+}
+
+func (b *cachingBody) discard() {
+	b.file.Close()
+	os.Remove(b.file.Name())
+	b.file = nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,212 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func TestFindFileCache(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		maxAge   time.Duration
+		maxStale time.Duration
+		failing  bool
+		expected find.CacheStats
+		requests int64
+	}{
+		{"revalidate", 0, 0, false, find.CacheStats{Revalidated: 2, Stores: 2}, 2},
+		{"max age", time.Hour, 0, false, find.CacheStats{Hits: 2}, 0},
+		{"max stale", 0, time.Hour, true, find.CacheStats{Stale: 2}, 2},
+		{"max stale exceeded", 0, 0, true, find.CacheStats{Misses: 1}, 1},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				requests int64
+				failing  int32
+			)
+
+			modified := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc(listingPath, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt64(&requests, 1)
+
+				switch {
+				case atomic.LoadInt32(&failing) == 1:
+					w.WriteHeader(http.StatusServiceUnavailable)
+				case r.Header.Get("If-None-Match") == `"root"`:
+					w.WriteHeader(http.StatusNotModified)
+				default:
+					w.Header().Set("ETag", `"root"`)
+					fmt.Fprint(w, `<a href="sub/">sub/</a><a href="root">root</a>`)
+				}
+			})
+			mux.HandleFunc(listingPath+"sub/", func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt64(&requests, 1)
+
+				switch {
+				case atomic.LoadInt32(&failing) == 1:
+					w.WriteHeader(http.StatusServiceUnavailable)
+				case r.Header.Get("If-Modified-Since") == modified:
+					w.WriteHeader(http.StatusNotModified)
+				default:
+					w.Header().Set("Last-Modified", modified)
+					fmt.Fprint(w, `<a href="sub">sub</a>`)
+				}
+			})
+
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			dir := t.TempDir()
+
+			run := func() (*find.Result, find.CacheStats, error) {
+				cache, err := find.NewCache(dir, http.DefaultTransport)
+				if err != nil {
+					return nil, find.CacheStats{}, err
+				}
+
+				cache.MaxAge = tc.maxAge
+				cache.MaxStale = tc.maxStale
+
+				found, err := find.NewFind(
+					find.WithSeedURLs([]string{server.URL + listingPath}),
+					find.WithFilenameRegexp(`.+`),
+					find.WithFileType(find.FileTypeReg),
+					find.WithRecursive(true),
+					find.WithClientTransport(cache),
+				).Find()
+
+				return found, cache.Stats(), err
+			}
+
+			// The first run fills the cache.
+			found, stats, err := run()
+
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, []string{"root", "sub"}, found.BaseNames)
+			assert.Equal(t, find.CacheStats{Misses: 2, Stores: 2}, stats)
+
+			atomic.StoreInt64(&requests, 0)
+
+			if tc.failing {
+				atomic.StoreInt32(&failing, 1)
+			}
+
+			found, stats, err = run()
+
+			assert.Equal(t, tc.expected, stats)
+			assert.Equal(t, tc.requests, atomic.LoadInt64(&requests))
+
+			if tc.expected.Misses > 0 {
+				assert.NotNil(t, err)
+
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, []string{"root", "sub"}, found.BaseNames)
+		})
+	}
+}
+
+func TestCachePrivate(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=3600")
+		case "/vary-any":
+			w.Header().Set("Vary", "*")
+		case "/vary":
+			w.Header().Set("Vary", "Accept-Language")
+		}
+
+		fmt.Fprint(w, r.Header.Get("Accept-Language")+r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	cache, err := find.NewCache(t.TempDir(), http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache.MaxAge = time.Hour
+
+	get := func(path string, header http.Header) (string, string) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header = header
+
+		resp, err := cache.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		return resp.Header.Get(find.CacheStatusHeader), string(body)
+	}
+
+	for _, tc := range []struct {
+		path   string
+		header http.Header
+		status find.CacheStatus
+		body   string
+	}{
+		{"/public", http.Header{}, find.CacheMiss, ""},
+		{"/public", http.Header{}, find.CacheHit, ""},
+		// The requests with credentials are neither served from the cache nor stored.
+		{"/public", http.Header{"Authorization": {"Bearer a"}}, "", "Bearer a"},
+		{"/public", http.Header{"Cookie": {"a=b"}}, "", ""},
+		{"/auth", http.Header{"Authorization": {"Bearer a"}}, "", "Bearer a"},
+		{"/auth", http.Header{}, find.CacheMiss, ""},
+		// The private responses and the ones varying by any request are not stored.
+		{"/private", http.Header{}, find.CacheMiss, ""},
+		{"/private", http.Header{}, find.CacheMiss, ""},
+		{"/vary-any", http.Header{}, find.CacheMiss, ""},
+		{"/vary-any", http.Header{}, find.CacheMiss, ""},
+		// The responses varying by request headers are stored for each value of the headers.
+		{"/vary", http.Header{"Accept-Language": {"en"}}, find.CacheMiss, "en"},
+		{"/vary", http.Header{"Accept-Language": {"en"}}, find.CacheHit, "en"},
+		{"/vary", http.Header{"Accept-Language": {"it"}}, find.CacheMiss, "it"},
+		{"/vary", http.Header{"Accept-Language": {"it"}}, find.CacheHit, "it"},
+	} {
+		status, body := get(tc.path, tc.header)
+
+		assert.Equal(t, string(tc.status), status, tc.path, tc.header)
+		assert.Equal(t, tc.body, body, tc.path, tc.header)
+	}
+}
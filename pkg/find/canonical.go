@@ -0,0 +1,228 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// indexFiles are the names of the files served by web servers as folder listings.
+var indexFiles = []string{"index.html", "index.htm"}
+
+// canonicalURL returns a copy of the absolute URL u in canonical form, that is with lower case
+// scheme and host, without default port, user info and fragment, with normalized percent-encoding,
+// and with dot segments and duplicate slashes removed from the path.
+func canonicalURL(u *url.URL) *url.URL {
+	c := *u
+	c.Scheme = strings.ToLower(u.Scheme)
+	c.Host = canonicalHost(c.Scheme, u.Host)
+	c.User = nil
+	c.Fragment = ""
+	c.RawFragment = ""
+
+	p := cleanPath(normalizeEscaping(u.EscapedPath()))
+	c.Path, _ = url.PathUnescape(p)
+	c.RawPath = p
+	c.RawQuery = normalizeEscaping(u.RawQuery)
+
+	return &c
+}
+
+// urlKey returns the key identifying the resource at the absolute URL u, that is its canonical form
+// with equivalent schemes and hosts folded together.
+func (o *Options) urlKey(u *url.URL) string {
+	return o.key(canonicalURL(u))
+}
+
+// folderKey returns the key identifying the folder at the absolute URL u, that is its key without
+// trailing index file, as web servers serve the index files as the listings of their folders.
+// Index files are real files too, so it's used only for the folders visited.
+func (o *Options) folderKey(u *url.URL) string {
+	c := canonicalURL(u)
+
+	for _, v := range indexFiles {
+		if strings.HasSuffix(c.RawPath, RootDir+v) {
+			c.RawPath = strings.TrimSuffix(c.RawPath, v)
+			c.Path, _ = url.PathUnescape(c.RawPath)
+
+			break
+		}
+	}
+
+	return o.key(c)
+}
+
+// key returns the key of the canonical URL c, with equivalent schemes and hosts folded together.
+func (o *Options) key(c *url.URL) string {
+	for alias, host := range o.HostAliases {
+		if canonicalHost(c.Scheme, alias) == c.Host {
+			c.Host = canonicalHost(c.Scheme, host)
+
+			break
+		}
+	}
+
+	if o.IgnoreScheme {
+		c.Scheme = ""
+	}
+
+	return c.String()
+}
+
+// canonicalHost returns the host in lower case, without the default port for the scheme.
+func canonicalHost(scheme, host string) string {
+	host = strings.ToLower(host)
+
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return host
+	}
+
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		if strings.Contains(hostname, ":") {
+			return "[" + hostname + "]"
+		}
+
+		return hostname
+	}
+
+	return host
+}
+
+// normalizeEscaping decodes the percent-encoded unreserved characters of s,
+// and upper cases the hexadecimal digits of the remaining percent-encodings.
+func normalizeEscaping(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) {
+			b.WriteByte(s[i])
+
+			continue
+		}
+
+		c, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			b.WriteByte(s[i])
+
+			continue
+		}
+
+		if isUnreserved(byte(c)) {
+			b.WriteByte(byte(c))
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+
+		i += 2
+	}
+
+	return b.String()
+}
+
+// isUnreserved returns whether c is an unreserved character as per RFC 3986.
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// cleanPath removes the dot segments and the duplicate slashes from the path p,
+// preserving its trailing slash.
+func cleanPath(p string) string {
+	segments := strings.Split(p, RootDir)
+	cleaned := make([]string, 0, len(segments))
+
+	for _, v := range segments {
+		switch v {
+		case "", ".":
+		case "..":
+			if len(cleaned) > 0 {
+				cleaned = cleaned[:len(cleaned)-1]
+			}
+		default:
+			cleaned = append(cleaned, v)
+		}
+	}
+
+	last := segments[len(segments)-1]
+	if len(cleaned) == 0 {
+		return RootDir
+	}
+
+	if last == "" || last == "." || last == ".." {
+		return RootDir + strings.Join(cleaned, RootDir) + RootDir
+	}
+
+	return RootDir + strings.Join(cleaned, RootDir)
+}
+
+// urlSet is a set of URL keys safe for concurrent use.
+type urlSet struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+func newURLSet() *urlSet {
+	return &urlSet{keys: map[string]struct{}{}}
+}
+
+// add adds the key to the set and returns whether it was not already present.
+func (s *urlSet) add(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[key]; ok {
+		return false
+	}
+
+	s.keys[key] = struct{}{}
+
+	return true
+}
+
+// remove removes the key from the set.
+func (s *urlSet) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key)
+}
+
+// list returns the keys of the set, sorted.
+func (s *urlSet) list() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.keys))
+	for k := range s.keys {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,53 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gocolly/colly"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// decodeBody converts to UTF-8 the body of the listing r, when served without charset
+// in the Content-Type header. The encoding is detected from the byte order mark or the
+// HTML meta tags, and falls back to Windows-1252, a superset of Latin-1.
+func decodeBody(r *colly.Response) {
+	contentType := r.Headers.Get("Content-Type")
+
+	// Listings with an explicit charset have already been converted by the collector.
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		return
+	}
+
+	if !strings.Contains(strings.ToLower(contentType), "html") || utf8.Valid(r.Body) {
+		return
+	}
+
+	// The body is not valid UTF-8, whatever the meta tags state.
+	e, name, _ := charset.DetermineEncoding(r.Body, contentType)
+	if name == "utf-8" {
+		e = charmap.Windows1252
+	}
+
+	if body, err := e.NewDecoder().Bytes(r.Body); err == nil {
+		r.Body = body
+	}
+}
@@ -0,0 +1,395 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// DefaultCheckpointInterval is the default interval between the checkpoints of a Find job.
+const DefaultCheckpointInterval = 30 * time.Second
+
+// pendingKey is the key of the request context storing the URL of the request, as visited.
+const pendingKey = "wfind.pending"
+
+// queueBatchSize is the number of the URLs visited at once from the queue of a QueueStorage.
+const queueBatchSize = 1000
+
+// Checkpoint is the state of a Find job, saved periodically so that the Find job can be resumed
+// once interrupted.
+type Checkpoint struct {
+	// SeedURLs, FilenameRegexp and FileType are the options of the Find job.
+	SeedURLs       []string `json:"seedURLs"`
+	FilenameRegexp string   `json:"filenameRegexp"`
+	FileType       string   `json:"fileType"`
+
+	// Visited are the keys of the URLs visited. They're missing if the Storage of the Find job
+	// doesn't list its keys, and the Find job resumed visits again the URLs.
+	Visited []string `json:"visited"`
+
+	// Pending are the URLs visited whose listings have not been examined yet, failed ones included,
+	// and the ones queued to be visited.
+	Pending []string `json:"pending"`
+
+	// Entries are the files found.
+	// Like Pending, they're held in memory while the checkpoint is saved, whatever the Storage.
+	Entries []Entry `json:"entries"`
+}
+
+// LoadCheckpoint returns the Checkpoint saved to the file at path.
+// If the file does not exist, the Checkpoint is empty and the Find job starts from scratch.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	c := &Checkpoint{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("error parsing the checkpoint %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Save writes the checkpoint to the file at path, replacing it atomically.
+func (c *Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(data); err == nil {
+		err = f.Chmod(0o600)
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
+
+// validate returns an error if the checkpoint is of a Find job with different options.
+func (c *Checkpoint) validate(o *Options) error {
+	if len(c.SeedURLs) == 0 {
+		return nil
+	}
+
+	if !reflect.DeepEqual(c.SeedURLs, o.SeedURLs) || c.FilenameRegexp != o.FilenameRegexp || c.FileType != o.FileType {
+		return ErrCheckpointMismatch
+	}
+
+	return nil
+}
+
+// crawlState is the state of a crawl: the URLs visited, the ones pending and the results.
+type crawlState struct {
+	o       *Options
+	storage Storage
+
+	// queue is the queue of the URLs to visit, if the storage is a QueueStorage.
+	queue QueueStorage
+
+	// mu makes the checkpoints exclusive with the URLs marked visited and pending, or queued,
+	// and the ones moved from the queue to pending, so that each URL visited is in a checkpoint
+	// either pending, queued, or examined.
+	mu sync.RWMutex
+
+	visited *storageSet
+	pending *urlSet
+	results *resultSet
+	resumed []string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newCrawlState returns the state of a crawl, resumed from the Resume checkpoint, if any.
+func (o *Options) newCrawlState() *crawlState {
+	storage := o.Storage
+	if storage == nil {
+		storage = NewMemoryStorage()
+	}
+
+	s := &crawlState{
+		o:       o,
+		storage: storage,
+		visited: &storageSet{storage: storage, prefix: visitedKeyPrefix},
+		pending: newURLSet(),
+		results: newResultSet(storage, o.MaxResults),
+	}
+
+	s.queue, _ = storage.(QueueStorage)
+
+	s.results.handler = o.EntryHandler
+
+	if o.Resume == nil {
+		return s
+	}
+
+	for _, v := range o.Resume.Visited {
+		s.visited.add(v)
+	}
+
+	// The URLs pending are visited again, queued if the storage queues them.
+	for _, v := range o.Resume.Pending {
+		if s.queue != nil {
+			s.push(v)
+
+			continue
+		}
+
+		s.pending.add(v)
+		s.resumed = append(s.resumed, v)
+	}
+
+	for _, v := range o.Resume.Entries {
+		if u, err := url.Parse(v.URL); err == nil {
+			s.results.add(o.urlKey(u), v)
+		}
+	}
+
+	return s
+}
+
+// visit visits the URL u with key, unless already visited, or queues it if the storage queues
+// the URLs to visit.
+func (s *crawlState) visit(co *colly.Collector, key, u string) error {
+	queued := s.queue != nil
+
+	if !s.mark(key, u, queued) || queued {
+		return nil
+	}
+
+	return co.Visit(u)
+}
+
+// fetch visits the URL u with key, unless already visited, keeping it pending until its listing
+// has been examined.
+func (s *crawlState) fetch(co *colly.Collector, key, u string) error {
+	if !s.mark(key, u, false) {
+		return nil
+	}
+
+	return co.Visit(u)
+}
+
+// mark marks the URL u with key as visited, unless already visited, and either as pending or
+// queued, at once for the checkpoints. It returns whether the URL was not visited yet.
+func (s *crawlState) mark(key, u string, queued bool) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.visited.add(key) {
+		return false
+	}
+
+	if queued {
+		s.push(u)
+	} else {
+		s.pending.add(u)
+	}
+
+	return true
+}
+
+// push queues the URL u. If the storage fails, the URL is kept pending, to be visited on resume.
+func (s *crawlState) push(u string) {
+	if err := s.queue.Push(u); err != nil {
+		log.Printf("error: storage: %v\n", err)
+		s.pending.add(u)
+	}
+}
+
+// wait waits until the URLs visited, and the ones queued, have been examined, visiting the queued
+// ones in batches until stopped.
+func (s *crawlState) wait(co *colly.Collector, stopped func() bool) {
+	co.Wait()
+
+	if s.queue == nil {
+		return
+	}
+
+	for !stopped() {
+		n := 0
+
+		for ; n < queueBatchSize; n++ {
+			u, ok, err := s.pop()
+			if err != nil {
+				log.Printf("error: storage: %v\n", err)
+
+				return
+			}
+
+			if !ok {
+				break
+			}
+
+			//nolint:errcheck
+			co.Visit(u)
+		}
+
+		if n == 0 {
+			return
+		}
+
+		co.Wait()
+	}
+}
+
+// pop pops a URL from the queue, and marks it as pending at once for the checkpoints.
+func (s *crawlState) pop() (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok, err := s.queue.Pop()
+	if ok {
+		s.pending.add(u)
+	}
+
+	return u, ok, err
+}
+
+// resume visits the URLs pending in the checkpoint, resuming the crawl.
+func (s *crawlState) resume(co *colly.Collector) {
+	for _, v := range s.resumed {
+		//nolint:errcheck
+		co.Visit(v)
+	}
+}
+
+// request marks the request with the URL it's pending as.
+func (s *crawlState) request(r *colly.Request) {
+	if r.Ctx.Get(pendingKey) == "" {
+		r.Ctx.Put(pendingKey, r.URL.String())
+	}
+}
+
+// scraped marks the listing of the response as examined.
+func (s *crawlState) scraped(r *colly.Response) {
+	s.pending.remove(r.Ctx.Get(pendingKey))
+}
+
+// checkpoint returns the checkpoint of the crawl.
+func (s *crawlState) checkpoint() *Checkpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &Checkpoint{
+		SeedURLs:       s.o.SeedURLs,
+		FilenameRegexp: s.o.FilenameRegexp,
+		FileType:       s.o.FileType,
+		Visited:        s.visited.list(),
+		Pending:        s.pendingList(),
+		Entries:        s.results.entries(),
+	}
+}
+
+// pendingList returns the URLs pending, followed by the ones queued.
+func (s *crawlState) pendingList() []string {
+	pending := s.pending.list()
+	if s.queue == nil {
+		return pending
+	}
+
+	queued, err := s.queue.Queued()
+	if err != nil {
+		log.Printf("error: storage: %v\n", err)
+	}
+
+	return append(pending, queued...)
+}
+
+// start saves the checkpoint of the crawl to the CheckpointFile every CheckpointInterval, if any.
+func (s *crawlState) start() {
+	if s.o.CheckpointFile == "" {
+		return
+	}
+
+	interval := s.o.CheckpointInterval
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.save()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// close stops saving the checkpoint of the crawl periodically, and saves the final one, once.
+func (s *crawlState) close() {
+	if s.stop == nil {
+		return
+	}
+
+	close(s.stop)
+	<-s.done
+
+	s.stop = nil
+
+	s.save()
+}
+
+func (s *crawlState) save() {
+	if err := s.checkpoint().Save(s.o.CheckpointFile); err != nil {
+		log.Printf("error saving the checkpoint: %v\n", err)
+	}
+}
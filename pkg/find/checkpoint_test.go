@@ -0,0 +1,255 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func TestFindFileCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		if r.URL.Path == listingPath {
+			fmt.Fprint(w, `<a href="a/">a/</a><a href="b/">b/</a><a href="c/">c/</a><a href="root">root</a>`)
+
+			return
+		}
+
+		fmt.Fprintf(w, `<a href="%[1]s">%[1]s</a>`, filepath.Base(r.URL.Path))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	options := []find.Option{
+		find.WithSeedURLs([]string{server.URL + listingPath}),
+		find.WithFilenameRegexp(`.+`),
+		find.WithFileType(find.FileTypeReg),
+		find.WithRecursive(true),
+		find.WithCheckpoint(path, 0),
+	}
+
+	// The first run is interrupted after two requests.
+	found, err := find.NewFind(append(options, find.WithMaxRequests(2))...).Find()
+
+	assert.Nil(t, err)
+	assert.True(t, found.Truncated)
+	assert.Len(t, found.BaseNames, 2)
+
+	checkpoint, err := find.LoadCheckpoint(path)
+
+	assert.Nil(t, err)
+	assert.Len(t, checkpoint.Pending, 2)
+	assert.Len(t, checkpoint.Entries, 2)
+
+	// The second run resumes from the checkpoint.
+	found, err = find.NewFind(append(options, find.WithResume(checkpoint))...).Find()
+
+	assert.Nil(t, err)
+	assert.False(t, found.Truncated)
+	assert.ElementsMatch(t, []string{"root", "a", "b", "c"}, found.BaseNames)
+
+	mu.Lock()
+	for k, v := range requests {
+		assert.Equal(t, 1, v, k)
+	}
+	mu.Unlock()
+
+	checkpoint, err = find.LoadCheckpoint(path)
+
+	assert.Nil(t, err)
+	assert.Empty(t, checkpoint.Pending)
+	assert.Len(t, checkpoint.Entries, 4)
+
+	// A checkpoint is resumed only by the same Find job.
+	_, err = find.NewFind(
+		find.WithSeedURLs([]string{server.URL + listingPath}),
+		find.WithFilenameRegexp(`root`),
+		find.WithFileType(find.FileTypeReg),
+		find.WithResume(checkpoint),
+	).Find()
+
+	assert.True(t, errors.Is(err, find.ErrCheckpointMismatch))
+}
+
+func TestFindFileCheckpointDefaults(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == listingPath {
+			fmt.Fprint(w, `<a href="a/">a/</a><a href="b/">b/</a><a href="root">root</a>`)
+
+			return
+		}
+
+		fmt.Fprintf(w, `<a href="%[1]s">%[1]s</a>`, filepath.Base(r.URL.Path))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	// The file type is left to its default, and the expression is anchored, so that
+	// both are normalized before the checkpoint is saved.
+	options := func() []find.Option {
+		return []find.Option{
+			find.WithSeedURLs([]string{server.URL + listingPath}),
+			find.WithFilenameRegexp(`^.+$`),
+			find.WithRecursive(true),
+			find.WithCheckpoint(path, 0),
+		}
+	}
+
+	found, err := find.NewFind(append(options(), find.WithMaxRequests(1))...).Find()
+
+	assert.Nil(t, err)
+	assert.True(t, found.Truncated)
+
+	checkpoint, err := find.LoadCheckpoint(path)
+
+	assert.Nil(t, err)
+	assert.NotEmpty(t, checkpoint.Pending)
+
+	found, err = find.NewFind(append(options(), find.WithResume(checkpoint))...).Find()
+
+	assert.Nil(t, err)
+	assert.False(t, found.Truncated)
+	assert.ElementsMatch(t, []string{"root", "a", "b"}, found.BaseNames)
+}
+
+func TestFindFileCheckpointQueue(t *testing.T) {
+	t.Parallel()
+
+	server := newSyntheticTree(2, 3, 1)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	options := func() []find.Option {
+		storage, err := find.NewDiskStorage(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() { storage.Close() })
+
+		return []find.Option{
+			find.WithSeedURLs([]string{server.URL + listingPath}),
+			find.WithFilenameRegexp(`.+\.tar\.gz`),
+			find.WithRecursive(true),
+			find.WithStorage(storage),
+			find.WithCheckpoint(path, 0),
+		}
+	}
+
+	// The folders of the seed are queued, and left in the queue once the budget is exhausted.
+	found, err := find.NewFind(append(options(), find.WithMaxRequests(1))...).Find()
+
+	assert.Nil(t, err)
+	assert.True(t, found.Truncated)
+
+	checkpoint, err := find.LoadCheckpoint(path)
+
+	assert.Nil(t, err)
+	assert.Len(t, checkpoint.Pending, 3)
+
+	found, err = find.NewFind(append(options(), find.WithResume(checkpoint))...).Find()
+
+	assert.Nil(t, err)
+	assert.False(t, found.Truncated)
+	assert.Len(t, found.URLs, 1+3+3*3)
+}
+
+func TestFindFileCheckpointPeriodic(t *testing.T) {
+	t.Parallel()
+
+	tree := newSyntheticTree(2, 3, 1)
+	defer tree.Close()
+
+	for _, name := range []string{"memory", "disk"} {
+		newStorage := storages(t)[name]
+		path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+		var (
+			mu          sync.Mutex
+			checkpoints [][]byte
+		)
+
+		// The checkpoints saved in the middle of the crawl are collected as the listings are served.
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(2 * time.Millisecond)
+
+			if data, err := os.ReadFile(path); err == nil {
+				mu.Lock()
+				checkpoints = append(checkpoints, data)
+				mu.Unlock()
+			}
+
+			tree.Config.Handler.ServeHTTP(w, r)
+		}))
+		defer server.Close()
+
+		options := func() []find.Option {
+			return []find.Option{
+				find.WithSeedURLs([]string{server.URL + listingPath}),
+				find.WithFilenameRegexp(`.+\.tar\.gz`),
+				find.WithRecursive(true),
+				find.WithAsync(true),
+				find.WithStorage(newStorage()),
+			}
+		}
+
+		found, err := find.NewFind(append(options(), find.WithCheckpoint(path, time.Millisecond))...).Find()
+
+		assert.Nil(t, err, name)
+		assert.Len(t, found.URLs, 1+3+3*3, name)
+		assert.NotEmpty(t, checkpoints, name)
+
+		// Each of them is resumed finding all the files.
+		for _, data := range checkpoints {
+			checkpoint := &find.Checkpoint{}
+			assert.Nil(t, json.Unmarshal(data, checkpoint), name)
+
+			found, err := find.NewFind(append(options(), find.WithResume(checkpoint))...).Find()
+
+			assert.Nil(t, err, name)
+			assert.Len(t, found.URLs, 1+3+3*3, "%s: resumed from %s", name, data)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// ErrorClass is the class of the failure of a request.
+type ErrorClass string
+
+const (
+	// ErrorClassContextDeadline is the class of the requests whose context deadline is exceeded.
+	ErrorClassContextDeadline ErrorClass = "context-deadline"
+
+	// ErrorClassDNS is the class of the requests whose host name can't be resolved.
+	ErrorClassDNS ErrorClass = "dns"
+
+	// ErrorClassTLS is the class of the requests whose TLS handshake or certificate verification fails.
+	ErrorClassTLS ErrorClass = "tls"
+
+	// ErrorClassConnRefused is the class of the requests whose connection is refused by the peer.
+	ErrorClassConnRefused ErrorClass = "connection-refused"
+
+	// ErrorClassConnReset is the class of the requests whose connection is reset (TCP RST) by the peer.
+	ErrorClassConnReset ErrorClass = "connection-reset"
+
+	// ErrorClassTimeout is the class of the requests timing out, based on the client timeout.
+	ErrorClassTimeout ErrorClass = "timeout"
+
+	// ErrorClassStatus is the class of the requests whose response has an HTTP error status code.
+	ErrorClassStatus ErrorClass = "status"
+
+	// ErrorClassCircuitOpen is the class of the requests failed fast because the circuit breaker
+	// of their host is open.
+	ErrorClassCircuitOpen ErrorClass = "circuit-open"
+
+	// ErrorClassTruncated is the class of the responses whose body has been truncated.
+	ErrorClassTruncated ErrorClass = "truncated"
+
+	// ErrorClassOther is the class of the other failures.
+	ErrorClassOther ErrorClass = "other"
+)
+
+// ErrorClasses are all the classes of failures.
+var ErrorClasses = []ErrorClass{
+	ErrorClassContextDeadline,
+	ErrorClassDNS,
+	ErrorClassTLS,
+	ErrorClassConnRefused,
+	ErrorClassConnReset,
+	ErrorClassTimeout,
+	ErrorClassStatus,
+	ErrorClassCircuitOpen,
+	ErrorClassTruncated,
+	ErrorClassOther,
+}
+
+// ParseErrorClass returns the ErrorClass named s.
+func ParseErrorClass(s string) (ErrorClass, error) {
+	for _, v := range ErrorClasses {
+		if strings.EqualFold(string(v), s) {
+			return v, nil
+		}
+	}
+
+	//nolint:goerr113
+	return "", fmt.Errorf("unknown error class %q", s)
+}
+
+// ClassifyError returns the class of the failure of a request, from the HTTP status code
+// of its response, if any, and its error.
+//
+//nolint:cyclop
+func ClassifyError(status int, err error) ErrorClass {
+	var (
+		dnsErr       *net.DNSError
+		verifyErr    *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+
+	switch {
+	case err == nil && status == 0:
+		return ErrorClassOther
+	case errors.Is(err, ErrCircuitOpen):
+		return ErrorClassCircuitOpen
+	case errors.Is(err, ErrBodyTruncated):
+		return ErrorClassTruncated
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassContextDeadline
+	case errors.As(err, &dnsErr):
+		return ErrorClassDNS
+	case errors.As(err, &verifyErr), errors.As(err, &recordErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return ErrorClassTLS
+	case errors.Is(err, unix.ECONNREFUSED):
+		return ErrorClassConnRefused
+	case errors.Is(err, unix.ECONNRESET):
+		return ErrorClassConnReset
+	case os.IsTimeout(err):
+		return ErrorClassTimeout
+	case status > 0:
+		return ErrorClassStatus
+	default:
+		return ErrorClassOther
+	}
+}
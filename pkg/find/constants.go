@@ -29,4 +29,9 @@ const (
 	FileTypeDir string = "d"
 
 	DefaultMaxBodySize = 1024 * 512
+
+	DefaultMaxListingSize = 1024 * 1024 * 256
+
+	// maxRedirects is the maximum number of redirects followed for each request.
+	maxRedirects = 10
 )
@@ -0,0 +1,322 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	netscapeCookieHeader = "# Netscape HTTP Cookie File"
+	netscapeHTTPOnly     = "#HttpOnly_"
+	netscapeFields       = 7
+)
+
+// cookieEntry is a cookie with the attributes needed to save it.
+type cookieEntry struct {
+	domain   string
+	hostOnly bool
+	path     string
+	secure   bool
+	httpOnly bool
+	expires  time.Time
+	name     string
+	value    string
+}
+
+func (e *cookieEntry) key() string {
+	return e.domain + ";" + e.path + ";" + e.name
+}
+
+// CookieJar is a cookie jar that can be loaded from and saved to files in the Netscape format.
+type CookieJar struct {
+	jar *cookiejar.Jar
+
+	mu      sync.Mutex
+	entries map[string]*cookieEntry
+}
+
+// NewCookieJar returns a new empty CookieJar.
+func NewCookieJar() *CookieJar {
+	//nolint:errcheck
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+
+	return &CookieJar{jar: jar, entries: map[string]*cookieEntry{}}
+}
+
+// LoadCookieJar returns a CookieJar with the cookies of the file in the Netscape format at path.
+// If the file does not exist, the CookieJar is empty.
+func LoadCookieJar(path string) (*CookieJar, error) {
+	j := NewCookieJar()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		httpOnly := strings.HasPrefix(line, netscapeHTTPOnly)
+		line = strings.TrimPrefix(line, netscapeHTTPOnly)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != netscapeFields {
+			//nolint:goerr113
+			return nil, fmt.Errorf("invalid cookie line %q in %s", line, path)
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie expiration %q in %s: %w", fields[4], path, err)
+		}
+
+		e := &cookieEntry{
+			domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			hostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			path:     fields[2],
+			secure:   strings.EqualFold(fields[3], "TRUE"),
+			httpOnly: httpOnly,
+			name:     fields[5],
+			value:    fields[6],
+		}
+
+		if expires > 0 {
+			e.expires = time.Unix(expires, 0)
+		}
+
+		j.load(e)
+	}
+
+	return j, scanner.Err()
+}
+
+// load adds the cookie entry e to the jar, unless it is expired.
+func (j *CookieJar) load(e *cookieEntry) {
+	if !e.expires.IsZero() && e.expires.Before(time.Now()) {
+		return
+	}
+
+	u := &url.URL{Scheme: "http", Host: e.domain, Path: e.path}
+	if e.secure {
+		u.Scheme = "https"
+	}
+
+	c := &http.Cookie{
+		Name:     e.name,
+		Value:    e.value,
+		Path:     e.path,
+		Expires:  e.expires,
+		Secure:   e.secure,
+		HttpOnly: e.httpOnly,
+	}
+	if !e.hostOnly {
+		c.Domain = e.domain
+	}
+
+	j.jar.SetCookies(u, []*http.Cookie{c})
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries[e.key()] = e
+}
+
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+
+	for _, c := range cookies {
+		e, ok := newCookieEntry(u, c)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case c.MaxAge < 0:
+			delete(j.entries, e.key())
+		case c.MaxAge > 0:
+			e.expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+			j.entries[e.key()] = e
+		case !c.Expires.IsZero() && c.Expires.Before(now):
+			delete(j.entries, e.key())
+		default:
+			e.expires = c.Expires
+			j.entries[e.key()] = e
+		}
+	}
+}
+
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Save writes the cookies of the jar in the Netscape format to the file at path.
+func (j *CookieJar) Save(path string) error {
+	j.mu.Lock()
+
+	entries := make([]*cookieEntry, 0, len(j.entries))
+	for _, v := range j.entries {
+		entries = append(entries, v)
+	}
+
+	j.mu.Unlock()
+
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].key() < entries[b].key()
+	})
+
+	var buf bytes.Buffer
+
+	fmt.Fprintln(&buf, netscapeCookieHeader)
+
+	now := time.Now()
+
+	for _, e := range entries {
+		if !e.expires.IsZero() && e.expires.Before(now) {
+			continue
+		}
+
+		domain := e.domain
+		if !e.hostOnly {
+			domain = "." + domain
+		}
+
+		if e.httpOnly {
+			domain = netscapeHTTPOnly + domain
+		}
+
+		var expires int64
+		if !e.expires.IsZero() {
+			expires = e.expires.Unix()
+		}
+
+		fmt.Fprintf(&buf, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, netscapeBool(!e.hostOnly), e.path, netscapeBool(e.secure), expires, e.name, e.value)
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+
+	return "FALSE"
+}
+
+// newCookieEntry returns the entry of the cookie c set by the URL u, and whether the
+// cookie is accepted, with the same domain rules of the cookie jar.
+func newCookieEntry(u *url.URL, c *http.Cookie) (*cookieEntry, bool) {
+	host := strings.ToLower(u.Hostname())
+
+	e := &cookieEntry{
+		domain:   host,
+		hostOnly: true,
+		path:     c.Path,
+		secure:   c.Secure,
+		httpOnly: c.HttpOnly,
+		name:     c.Name,
+		value:    c.Value,
+	}
+
+	if domain := strings.ToLower(strings.TrimPrefix(c.Domain, ".")); domain != "" && domain != host {
+		if !strings.HasSuffix(host, "."+domain) {
+			return nil, false
+		}
+
+		if suffix, _ := publicsuffix.PublicSuffix(domain); suffix == domain {
+			return nil, false
+		}
+
+		e.domain = domain
+		e.hostOnly = false
+	} else if domain != "" {
+		e.hostOnly = false
+	}
+
+	if !strings.HasPrefix(e.path, "/") {
+		e.path = defaultCookiePath(u.Path)
+	}
+
+	return e, true
+}
+
+// defaultCookiePath returns the default path of the cookies set by a URL with path p, as of RFC 6265.
+func defaultCookiePath(p string) string {
+	i := strings.LastIndex(p, "/")
+	if !strings.HasPrefix(p, "/") || i == 0 {
+		return "/"
+	}
+
+	return p[:i]
+}
+
+// cookieTransport is an HTTP transport sending and storing the cookies of a jar.
+// Being in the transport, the jar is used on each redirect too.
+type cookieTransport struct {
+	jar  http.CookieJar
+	next http.RoundTripper
+}
+
+func (t *cookieTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if cookies := t.jar.Cookies(req.URL); len(cookies) > 0 {
+		req = req.Clone(req.Context())
+
+		for _, v := range cookies {
+			req.AddCookie(v)
+		}
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cookies := res.Cookies(); len(cookies) > 0 {
+		t.jar.SetCookies(req.URL, cookies)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vitorsalgado/mocha/v3"
+	"github.com/vitorsalgado/mocha/v3/expect"
+	"github.com/vitorsalgado/mocha/v3/reply"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func TestFindFileHeaders(t *testing.T) {
+	t.Parallel()
+
+	m := mocha.New(t).CloseOnCleanup(t)
+	m.Start()
+
+	m.AddMocks(
+		mocha.Get(expect.URLPath(listingPath)).
+			Header("User-Agent", expect.ToEqual("wfind-test")).
+			Header("X-Mirror-Token", expect.ToEqual("secret")).
+			Reply(reply.OK().BodyString(`<a href="file">file</a>`)))
+
+	finder := find.NewFind(
+		find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+		find.WithFilenameRegexp(`.+`),
+		find.WithFileType(find.FileTypeReg),
+		find.WithRecursive(true),
+		find.WithUserAgent("wfind-test"),
+		find.WithHeaders(http.Header{"X-Mirror-Token": {"secret"}}),
+	)
+
+	found, err := finder.Find()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, found)
+	assert.Equal(t, []string{"file"}, found.BaseNames)
+}
+
+func TestFindFileCookies(t *testing.T) {
+	t.Parallel()
+
+	m := mocha.New(t).CloseOnCleanup(t)
+	m.Start()
+
+	m.AddMocks(
+		mocha.Get(expect.URLPath(listingPath)).
+			Header("Cookie", expect.ToEqual("consent=yes")).
+			Reply(reply.OK().
+				Header("Set-Cookie", "session=abc; Path=/; Max-Age=3600").
+				BodyString(`<a href="sub/">sub/</a>`)),
+		mocha.Get(expect.URLPath(listingPath+"sub/")).
+			Header("Cookie", expect.ToContain("session=abc")).
+			Reply(reply.OK().BodyString(`<a href="file">file</a>`)))
+
+	jar := find.NewCookieJar()
+
+	finder := find.NewFind(
+		find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+		find.WithFilenameRegexp(`.+`),
+		find.WithFileType(find.FileTypeReg),
+		find.WithRecursive(true),
+		find.WithCookies([]*http.Cookie{{Name: "consent", Value: "yes", Path: listingPath}}),
+		find.WithCookieJar(jar),
+	)
+
+	found, err := finder.Find()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, found)
+	assert.Equal(t, []string{"file"}, found.BaseNames)
+
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	assert.Nil(t, jar.Save(path))
+
+	loaded, err := find.LoadCookieJar(path)
+	assert.Nil(t, err)
+
+	u, _ := url.Parse(m.URL() + "/other/")
+	cookies := loaded.Cookies(u)
+
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, "session", cookies[0].Name)
+	assert.Equal(t, "abc", cookies[0].Value)
+}
@@ -0,0 +1,191 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/gocolly/colly"
+	d "github.com/gocolly/colly/debug"
+	"github.com/pkg/errors"
+)
+
+// crawl returns the entries found from the seed URLs, examining the listings top-down,
+// that are matched by the match stage.
+//
+//nolint:funlen,cyclop
+func (o *Options) crawl() (*Result, error) {
+	seeds := []*url.URL{}
+
+	for _, v := range o.SeedURLs {
+		u, _ := url.Parse(v)
+
+		seeds = append(seeds, u)
+	}
+
+	if len(getHostnamesFromURLs(seeds)) < 1 {
+		return nil, ErrNoSeeds
+	}
+
+	// Select the entries of the types searched, then the ones matched.
+	reg, dir, _ := parseFileType(o.FileType)
+
+	matcher, err := o.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	searched := func(entry Entry) bool {
+		if entry.Type == FileTypeDir {
+			return dir
+		}
+
+		return reg
+	}
+
+	// The crawl is resumed from the checkpoint, if any.
+	state := o.newCrawlState()
+	results := state.results
+
+	folderPattern := regexp.MustCompile(folderRegex)
+
+	seedScope := newScope(seeds, o.AllowedDomains)
+
+	budget := o.newCrawlBudget()
+	defer budget.close()
+
+	// Create the collector settings
+	coOptions := []func(*colly.Collector){
+		colly.Async(o.Async),
+		// The bodies are limited by the transport.
+		colly.MaxBodySize(0),
+	}
+
+	if o.UserAgent != "" {
+		coOptions = append(coOptions, colly.UserAgent(o.UserAgent))
+	}
+
+	if o.Verbose {
+		coOptions = append(coOptions, colly.Debugger(&d.LogDebugger{}))
+	}
+
+	// Create the collector, managing the cookies in its transport.
+	co := colly.NewCollector(coOptions...)
+	co.WithTransport(budget.transport(o.transport(seeds)))
+
+	// Keep the requests visited in the Storage. Setting it resets the cookie jar of the collector,
+	// so its cookies are disabled afterwards.
+	//nolint:errcheck
+	co.SetStorage(&collyStorage{storage: state.storage})
+	co.DisableCookies()
+
+	// Follow redirects to allowed hosts only.
+	co.RedirectHandler = o.redirectHandler(seedScope)
+
+	// Visit only folders of allowed hosts within the budget, authenticating to them.
+	co.OnRequest(func(r *colly.Request) {
+		state.request(r)
+
+		if !seedScope.allows(r.URL) || !folderPattern.MatchString(r.URL.String()) {
+			r.Abort()
+
+			return
+		}
+
+		for k, v := range o.Headers {
+			(*r.Headers)[http.CanonicalHeaderKey(k)] = append([]string{}, v...)
+		}
+
+		if err := o.authorize(seedScope, r.URL, *r.Headers); err != nil {
+			log.Printf("error: %v\n", err)
+			r.Abort()
+
+			return
+		}
+
+		// Only the requests actually made are accounted.
+		budget.request(r)
+	})
+
+	// Report truncated listings and convert them to UTF-8.
+	co.OnResponse(o.truncationHandler(results))
+	co.OnResponse(decodeBody)
+
+	// Match the entry of each link, and traverse the folder hierarchy in top-down order.
+	co.OnHTML(HTMLTagLink, func(e *colly.HTMLElement) {
+		href := e.Attr(HTMLAttrRef)
+
+		hrefAbsURL, ok := o.follow(seedScope, e.Request.URL, href)
+		if !ok {
+			return
+		}
+
+		entry := newEntry(hrefAbsURL, href, folderPattern.MatchString(href))
+		entry.ModTime, entry.Size = parseListingColumns(listingColumns(e.DOM.Nodes[0]))
+
+		// The seeds are not entries of their own listings.
+		if !o.urlSliceContains(seeds, hrefAbsURL) && searched(entry) && matcher.Match(entry) {
+			if results.add(o.urlKey(hrefAbsURL), entry) {
+				budget.stop(TruncateMaxResults)
+			}
+		}
+
+		if o.Recursive && entry.Type == FileTypeDir {
+			//nolint:errcheck
+			state.visit(co, o.folderKey(hrefAbsURL), entry.URL)
+		}
+	})
+
+	co.OnResponse(budget.response)
+
+	// Keep the listings pending until examined.
+	co.OnScraped(state.scraped)
+
+	state.start()
+	defer state.close()
+
+	// Manage errors.
+	co.OnError(o.errorHandler(newRetryBudget(o.RetryBudget), budget, results))
+
+	// Visit each root folder.
+	for _, seedURL := range seeds {
+		// Failed requests are reported with the results.
+		failures := results.failures()
+
+		err := state.fetch(co, o.folderKey(seedURL), seedURL.String())
+		if err != nil && results.failures() == failures && !errors.Is(err, ErrRobotsDisallowed) && !budget.exhausted() {
+			return nil, errors.Wrap(err, fmt.Sprintf("error scraping URL %s", seedURL.String()))
+		}
+	}
+
+	state.resume(co)
+
+	// Wait until the listings visited and queued are examined.
+	state.wait(co, budget.exhausted)
+
+	// The last checkpoint is saved before the results are sorted.
+	state.close()
+
+	result, err := results.get(budget.truncated())
+	result.Sort(o.Sort, o.Reverse)
+
+	return result, err
+}
@@ -0,0 +1,157 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vitorsalgado/mocha/v3"
+	"github.com/vitorsalgado/mocha/v3/expect"
+	"github.com/vitorsalgado/mocha/v3/reply"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+// initTypedListing returns a mock server serving a listing with a file and a folder,
+// referenced both relative and absolute, and a file in the folder.
+func initTypedListing(t *testing.T) *mocha.Mocha {
+	t.Helper()
+
+	m := mocha.New(t).CloseOnCleanup(t)
+	m.Start()
+
+	m.AddMocks(
+		mocha.Get(expect.URLPath(listingPath)).
+			Reply(reply.OK().BodyString(fmt.Sprintf(
+				`<a href="../">../</a><a href="%s">.</a><a href="sub/">sub/</a><a href="%sabs/">abs/</a><a href="file">file</a>`,
+				listingPath, listingPath))),
+		mocha.Get(expect.URLPath(listingPath+"sub/")).
+			Reply(reply.OK().BodyString(`<a href="../">../</a><a href="./subfile">subfile</a>`)),
+		mocha.Get(expect.URLPath(listingPath+"abs/")).
+			Reply(reply.OK().BodyString(`<a href="../">../</a>`)))
+
+	return m
+}
+
+func TestFindFileTypes(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		fileType  string
+		regexp    string
+		recursive bool
+		expected  []string
+	}{
+		{"", `.+`, false, []string{"f:file"}},
+		{find.FileTypeReg, `.+`, true, []string{"f:file", "f:subfile"}},
+		{find.FileTypeDir, `.+`, true, []string{"d:abs", "d:sub"}},
+		{"f,d", `.+`, false, []string{"d:abs", "d:sub", "f:file"}},
+		{"d,f", `.+`, true, []string{"d:abs", "d:sub", "f:file", "f:subfile"}},
+		// Anchored expressions match relative and absolute references.
+		{find.FileTypeDir, `^abs$`, false, []string{"d:abs"}},
+		{find.FileTypeReg, `^sub`, true, []string{"f:subfile"}},
+		{"f,d", `^sub`, true, []string{"d:sub", "f:subfile"}},
+	} {
+		m := initTypedListing(t)
+
+		finder := find.NewFind(
+			find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+			find.WithFilenameRegexp(tc.regexp),
+			find.WithFileType(tc.fileType),
+			find.WithRecursive(tc.recursive),
+		)
+
+		found, err := finder.Find()
+
+		assert.Nil(t, err)
+		assert.NotNil(t, found)
+
+		entries := []string{}
+		for _, v := range found.Entries {
+			entries = append(entries, v.Type+":"+v.Name)
+		}
+
+		assert.ElementsMatch(t, tc.expected, entries, "type %q, regexp %q", tc.fileType, tc.regexp)
+	}
+}
+
+func TestFindFileAnchoredName(t *testing.T) {
+	t.Parallel()
+
+	m := mocha.New(t).CloseOnCleanup(t)
+	m.Start()
+
+	m.AddMocks(
+		mocha.Get(expect.URLPath(listingPath)).
+			Reply(reply.OK().BodyString(fmt.Sprintf(
+				`<a href="foo">foo</a><a href="./foo-dot">foo-dot</a><a href="%[1]sfoo-abs">foo-abs</a>`+
+					`<a href="%[2]s%[1]sfoo-url">foo-url</a><a href="foo-dir/">foo-dir/</a>`+
+					`<a href="barfoo">barfoo</a><a href="%[1]sbarfoo-abs">barfoo-abs</a><a href="barfoo-dir/">barfoo-dir/</a>`,
+				listingPath, m.URL()))))
+
+	found, err := find.NewFind(
+		find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+		find.WithFilenameRegexp(`^foo`),
+		find.WithFileType("f,d"),
+	).Find()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, found)
+
+	// The expression is anchored to the start of the name, whether the reference is relative or absolute.
+	assert.ElementsMatch(t, []string{"foo", "foo-dot", "foo-abs", "foo-url", "foo-dir"}, found.BaseNames)
+}
+
+func TestFindFileUnsupportedType(t *testing.T) {
+	t.Parallel()
+
+	for _, fileType := range []string{"l", "f,l", "f,"} {
+		finder := find.NewFind(
+			find.WithSeedURLs([]string{"http://localhost" + listingPath}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(fileType),
+		)
+
+		_, err := finder.Find()
+
+		assert.ErrorIs(t, err, find.ErrUnsupportedFileType, fileType)
+	}
+}
+
+func TestFindFileMatcher(t *testing.T) {
+	t.Parallel()
+
+	m := initTypedListing(t)
+
+	finder := find.NewFind(
+		find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+		find.WithFileType("f,d"),
+		find.WithRecursive(true),
+		find.WithMatcher(find.MatcherFunc(func(entry find.Entry) bool {
+			return strings.HasPrefix(entry.Name, "sub")
+		})),
+	)
+
+	found, err := finder.Find()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, found)
+	assert.ElementsMatch(t, []string{"sub", "subfile"}, found.BaseNames)
+}
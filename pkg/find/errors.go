@@ -0,0 +1,148 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoSeeds is returned when no seed URLs are specified.
+	ErrNoSeeds = errors.New("no seed URLs specified")
+
+	// ErrNoFilenameRegexp is returned when no file name regular expression is specified.
+	ErrNoFilenameRegexp = errors.New("no filename regular expression specified")
+
+	// ErrUnsupportedFileType is returned when the file type is not supported.
+	ErrUnsupportedFileType = errors.New("file type not supported")
+
+	// ErrUnsupportedSortKey is returned when the sort key is not supported.
+	ErrUnsupportedSortKey = errors.New("sort key not supported")
+
+	// ErrNegativeParallelism is returned when the parallelism is negative.
+	ErrNegativeParallelism = errors.New("negative parallelism")
+
+	// ErrNegativeBudget is returned when a budget of the Find job is negative.
+	ErrNegativeBudget = errors.New("negative budget")
+
+	// ErrCircuitOpen is the error of the requests failed fast because the circuit breaker
+	// of their host is open.
+	ErrCircuitOpen = errors.New("circuit breaker open")
+
+	// ErrRobotsDisallowed is the error of the requests skipped because disallowed by the
+	// robots.txt of their host.
+	ErrRobotsDisallowed = errors.New("disallowed by robots.txt")
+
+	// ErrBodyTruncated is the error of the responses whose body exceeds the maximum size
+	// and has been truncated.
+	ErrBodyTruncated = errors.New("body truncated")
+
+	// ErrCheckpointMismatch is returned when the checkpoint to resume is of a Find job with
+	// different seed URLs, file name expression or file type.
+	ErrCheckpointMismatch = errors.New("checkpoint of a different find job")
+)
+
+// ErrInvalidSeed is returned when a seed URL is not a valid HTTP or HTTPS URL.
+type ErrInvalidSeed struct {
+	// URL is the invalid seed URL.
+	URL string
+
+	// Err is the error parsing the seed URL, if any.
+	Err error
+}
+
+func (e *ErrInvalidSeed) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("invalid seed URL %q: %v", e.URL, e.Err)
+	}
+
+	return fmt.Sprintf("invalid seed URL %q: not an HTTP or HTTPS URL", e.URL)
+}
+
+func (e *ErrInvalidSeed) Unwrap() error {
+	return e.Err
+}
+
+// ErrInvalidFilenameRegexp is returned when the file name regular expression doesn't compile.
+type ErrInvalidFilenameRegexp struct {
+	// Expr is the invalid file name regular expression.
+	Expr string
+
+	// Err is the error compiling the expression.
+	Err error
+}
+
+func (e *ErrInvalidFilenameRegexp) Error() string {
+	return fmt.Sprintf("invalid file name expression %q: %v", e.Expr, e.Err)
+}
+
+func (e *ErrInvalidFilenameRegexp) Unwrap() error {
+	return e.Err
+}
+
+// FetchError represents a request failed after all its attempts.
+type FetchError struct {
+	// URL is the URL of the request.
+	URL string
+
+	// Status is the HTTP status code of the response, if any.
+	Status int
+
+	// Attempts is the number of attempts of the request made.
+	Attempts int
+
+	// Class is the class of the failure.
+	Class ErrorClass
+
+	// Err is the error of the last attempt.
+	Err error
+}
+
+func (e *FetchError) Error() string {
+	if e.Status != 0 {
+		return fmt.Sprintf("error fetching %s after %d attempts: status %d: %v", e.URL, e.Attempts, e.Status, e.Err)
+	}
+
+	return fmt.Sprintf("error fetching %s after %d attempts: %s: %v", e.URL, e.Attempts, e.Class, e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// PartialResultError is returned by the Find job along with the Result when some requests
+// failed, so that the Result lacks their subtrees.
+type PartialResultError struct {
+	// Failures are the failed requests.
+	Failures []Failure
+}
+
+func (e *PartialResultError) Error() string {
+	return fmt.Sprintf("partial result: %d requests failed", len(e.Failures))
+}
+
+// Unwrap returns the FetchError of each failed request.
+func (e *PartialResultError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+
+	for k := range e.Failures {
+		errs = append(errs, e.Failures[k].FetchError())
+	}
+
+	return errs
+}
@@ -0,0 +1,118 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vitorsalgado/mocha/v3"
+	"github.com/vitorsalgado/mocha/v3/expect"
+	"github.com/vitorsalgado/mocha/v3/reply"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func TestFindInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		seeds    []string
+		regexp   string
+		fileType string
+		expected error
+	}{
+		{nil, ".+", find.FileTypeReg, find.ErrNoSeeds},
+		{[]string{"http://localhost/"}, "", find.FileTypeReg, find.ErrNoFilenameRegexp},
+		{[]string{"http://localhost/"}, ".+", "l", find.ErrUnsupportedFileType},
+	} {
+		_, err := find.NewFind(
+			find.WithSeedURLs(tc.seeds),
+			find.WithFilenameRegexp(tc.regexp),
+			find.WithFileType(tc.fileType),
+		).Find()
+
+		assert.ErrorIs(t, err, tc.expected)
+	}
+
+	for _, seed := range []string{"ftp://localhost/", "localhost/pub/", "http://%zz/"} {
+		_, err := find.NewFind(
+			find.WithSeedURLs([]string{seed}),
+			find.WithFilenameRegexp(".+"),
+		).Find()
+
+		var invalidSeed *find.ErrInvalidSeed
+
+		assert.ErrorAs(t, err, &invalidSeed)
+		assert.Equal(t, seed, invalidSeed.URL)
+	}
+
+	_, err := find.NewFind(
+		find.WithSeedURLs([]string{"http://localhost/"}),
+		find.WithFilenameRegexp("("),
+	).Find()
+
+	var invalidRegexp *find.ErrInvalidFilenameRegexp
+
+	assert.ErrorAs(t, err, &invalidRegexp)
+	assert.Equal(t, "(", invalidRegexp.Expr)
+
+	for _, tc := range []struct {
+		option   find.Option
+		expected error
+	}{
+		{find.WithParallelism(-1), find.ErrNegativeParallelism},
+		{find.WithMaxRequests(-1), find.ErrNegativeBudget},
+	} {
+		_, err := find.NewFind(
+			find.WithSeedURLs([]string{"http://localhost/"}),
+			find.WithFilenameRegexp(".+"),
+			tc.option,
+		).Find()
+
+		assert.ErrorIs(t, err, tc.expected)
+	}
+}
+
+func TestFindFetchError(t *testing.T) {
+	t.Parallel()
+
+	m := mocha.New(t).CloseOnCleanup(t)
+	m.Start()
+
+	m.AddMocks(
+		mocha.Get(expect.URLPath(listingPath)).
+			Reply(reply.Status(http.StatusNotFound)))
+
+	seed := fmt.Sprintf("%s%s", m.URL(), listingPath)
+
+	_, err := find.NewFind(
+		find.WithSeedURLs([]string{seed}),
+		find.WithFilenameRegexp(".+"),
+		find.WithAsync(true),
+	).Find()
+
+	var fetchErr *find.FetchError
+
+	assert.True(t, errors.As(err, &fetchErr))
+	assert.Equal(t, seed, fetchErr.URL)
+	assert.Equal(t, http.StatusNotFound, fetchErr.Status)
+	assert.Equal(t, 1, fetchErr.Attempts)
+}
@@ -19,8 +19,11 @@ package find
 import (
 	"net/http"
 	"net/url"
+	"path"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/maxgio92/wfind/internal/network"
 	"github.com/pkg/errors"
@@ -33,6 +36,125 @@ type Result struct {
 
 	// URLs are the universal resource location of the files found.
 	URLs []string
+
+	// Entries are the files found.
+	Entries []Entry
+
+	// Failures are the requests failed, whose subtrees are missing from the Result.
+	Failures []Failure
+
+	// Truncated reports whether the Find job stopped before examining the whole hierarchy,
+	// as one of its budgets has been exhausted.
+	Truncated bool
+
+	// TruncateReason is the budget exhausted, if the Result is truncated.
+	TruncateReason TruncateReason
+}
+
+// Entry represents a file or folder found by the Find job.
+type Entry struct {
+	// Name is the percent-decoded path base of the file.
+	Name string
+
+	// URL is the universal resource location of the file.
+	URL string
+
+	// Href is the reference to the file, as found in the listing.
+	Href string
+
+	// Type is the file type of the entry, either FileTypeReg or FileTypeDir.
+	Type string
+
+	// Size is the size in bytes of the file, as reported by the listing, zero if unknown, like when
+	// the listings are streamed or served from a Snapshot.
+	Size int64
+
+	// ModTime is the modification time of the file, as reported by the listing, zero if unknown.
+	ModTime time.Time
+}
+
+// resultSet accumulates the files found by the Find job, skipping duplicates.
+// It's safe for concurrent use.
+type resultSet struct {
+	keys  *storageSet
+	limit int
+
+	// handler is called with each entry added.
+	handler func(Entry)
+
+	mu     sync.Mutex
+	result Result
+}
+
+// newResultSet returns a resultSet of up to limit entries, whose keys are stored in storage.
+// Zero means no limit.
+func newResultSet(storage Storage, limit int) *resultSet {
+	return &resultSet{keys: &storageSet{storage: storage, prefix: resultKeyPrefix}, limit: limit}
+}
+
+// add adds the entry, identified by key, unless already present or the limit is reached.
+// It returns whether the limit is reached.
+func (r *resultSet) add(key string, entry Entry) bool {
+	if !r.keys.add(key) {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.limit > 0 && len(r.result.Entries) >= r.limit {
+		return true
+	}
+
+	r.result.BaseNames = append(r.result.BaseNames, entry.Name)
+	r.result.URLs = append(r.result.URLs, entry.URL)
+	r.result.Entries = append(r.result.Entries, entry)
+
+	if r.handler != nil {
+		r.handler(entry)
+	}
+
+	return r.limit > 0 && len(r.result.Entries) >= r.limit
+}
+
+// fail adds the failure of a request.
+func (r *resultSet) fail(f *Failure) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.result.Failures = append(r.result.Failures, *f)
+}
+
+// failures returns the number of the failed requests.
+func (r *resultSet) failures() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.result.Failures)
+}
+
+// entries returns the entries added.
+func (r *resultSet) entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Entry{}, r.result.Entries...)
+}
+
+// get returns the Result, truncated for the reason if not empty,
+// along with a PartialResultError if some requests failed.
+func (r *resultSet) get(reason TruncateReason) (*Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.result.Truncated = reason != ""
+	r.result.TruncateReason = reason
+
+	if len(r.result.Failures) > 0 {
+		return &r.result, &PartialResultError{Failures: r.result.Failures}
+	}
+
+	return &r.result, nil
 }
 
 // Options represents the options for the Find job.
@@ -44,14 +166,132 @@ type Options struct {
 	FilenameRegexp string
 
 	// FileType is the file type for which the Find job examines the web hierarchy.
+	// Both regular files and folders are searched with the comma-separated FileTypeReg and FileTypeDir.
 	FileType string
 
+	// Matcher is the match stage selecting the entries of the Result. By default the entries
+	// whose names match FilenameRegexp are selected.
+	Matcher Matcher
+
+	// Sort is the key the entries of the Result are sorted by, SortPath by default.
+	Sort SortKey
+
+	// Reverse reverses the order of the entries of the Result.
+	Reverse bool
+
+	// EntryHandler is called with each entry as soon as it's found, in the order they're found,
+	// so that they can be streamed without waiting for the Result. The calls are serialized.
+	EntryHandler func(Entry)
+
 	// Recursive enables the Find job to examine files referenced to by the seeds files recursively.
 	Recursive bool
 
 	// Verbose enables the Find job verbosity printing every visited URL.
 	Verbose bool
 
+	// AllowEscape enables the Find job to examine files outside the path of the seed URLs.
+	// By default, the Find job is confined to the hierarchy below each seed URL.
+	AllowEscape bool
+
+	// AllowedDomains are the hosts, in addition to the ones of the seed URLs, the Find job is allowed
+	// to examine, following redirects and external links. Wildcard patterns like *.kernel.org are supported.
+	AllowedDomains []string
+
+	// FollowExternalLinks enables the Find job to follow links to allowed hosts other than the one of the listing.
+	FollowExternalLinks bool
+
+	// IgnoreScheme makes the Find job consider the same resource over HTTP and HTTPS as equivalent.
+	IgnoreScheme bool
+
+	// HostAliases maps host aliases to the hosts they are equivalent to.
+	HostAliases map[string]string
+
+	// Credentials provides the credentials to authenticate to the hosts.
+	// Credentials are sent only to the host they are provided for, also across redirects.
+	Credentials CredentialProvider
+
+	// Headers are the additional headers sent with each request.
+	// An Authorization header is sent only to the hosts of the seed URLs, and overridden
+	// for the hosts having Credentials.
+	Headers http.Header
+
+	// UserAgent is the User-Agent sent with each request. If empty, the one of the collector is sent.
+	UserAgent string
+
+	// Cookies are the cookies sent to the hosts of the seed URLs.
+	Cookies []*http.Cookie
+
+	// CookieJar stores the cookies sent and received. If nil, cookies are kept only during the Find job.
+	CookieJar http.CookieJar
+
+	// Parallelism is the maximum number of concurrent requests overall. Zero means no limit.
+	Parallelism int
+
+	// PerHostParallelism is the maximum number of concurrent requests to each host. Zero means no limit.
+	PerHostParallelism int
+
+	// Delay is the time to wait after each request to a host, before making the next one.
+	Delay time.Duration
+
+	// RandomDelay is the maximum random time added to the Delay.
+	RandomDelay time.Duration
+
+	// Rate is the maximum number of requests per second to each host. Zero means no limit.
+	Rate float64
+
+	// LimitRules override the limits to each host for the hosts matching them. The first matching rule applies.
+	LimitRules []*LimitRule
+
+	// AdaptiveConcurrency enables the Find job to adapt the concurrent requests to each host, up to
+	// its parallelism, increasing them while latency and errors are healthy, and halving them on
+	// timeouts, connection resets and 429 or 503 status codes.
+	AdaptiveConcurrency bool
+
+	// CircuitBreakerThreshold is the number of consecutive failures of the requests to a host
+	// after which the requests to the host fail fast, for the CircuitBreakerCooldown.
+	// Zero disables the circuit breaker.
+	CircuitBreakerThreshold int
+
+	// CircuitBreakerCooldown is the time after which a host whose requests fail fast is probed again.
+	// If zero, DefaultCircuitBreakerCooldown is used.
+	CircuitBreakerCooldown time.Duration
+
+	// Storage stores the keys of the URLs visited and of the files found. If nil, they're kept in memory.
+	// A Storage is used by a single Find job.
+	Storage Storage
+
+	// Snapshot records the listings examined, and makes the Find job skip the subtrees whose listings
+	// are not modified since the Snapshot, reusing their previous links. If nil, all listings are retrieved.
+	Snapshot *Snapshot
+
+	// CheckpointFile is the path of the file the state of the Find job is saved to,
+	// every CheckpointInterval and once finished, so that the Find job can be resumed.
+	CheckpointFile string
+
+	// CheckpointInterval is the interval between the checkpoints. If zero, DefaultCheckpointInterval is used.
+	CheckpointInterval time.Duration
+
+	// Resume is the checkpoint of an interrupted Find job to resume, as loaded by LoadCheckpoint.
+	Resume *Checkpoint
+
+	// MaxRequests is the maximum number of requests of the Find job, retries included.
+	// Zero means no limit.
+	MaxRequests int
+
+	// MaxBytes is the maximum number of bytes of the response bodies read by the Find job.
+	// Zero means no limit.
+	MaxBytes int64
+
+	// MaxResults is the number of files found after which the Find job stops. Zero means no limit.
+	MaxResults int
+
+	// Timeout is the maximum duration of the Find job. Zero means no limit.
+	Timeout time.Duration
+
+	// RespectRobots makes the Find job skip the URLs disallowed by the robots.txt of their host,
+	// and wait for its Crawl-delay between the requests to the host.
+	RespectRobots bool
+
 	// Async represetns the option to scrape with multiple asynchronous coroutines.
 	Async bool
 
@@ -59,8 +299,23 @@ type Options struct {
 	ClientTransport http.RoundTripper
 
 	// MaxBodySize is the limit in bytes of each of the retrieved response body.
+	// The listings truncated are reported as failures, as their entries past the limit are missing.
 	MaxBodySize int
 
+	// StreamListings makes the Find job parse the listings while they're retrieved, keeping only
+	// their links, so that they're limited by MaxListingSize instead of MaxBodySize.
+	StreamListings bool
+
+	// MaxListingSize is the limit in bytes of each of the retrieved listings, when StreamListings is enabled.
+	MaxListingSize int64
+
+	// RetryPolicy decides whether to retry the failed requests.
+	// It's preferred to the backoff options, which are retry policies for specific failures.
+	RetryPolicy RetryPolicy
+
+	// RetryBudget is the maximum number of retries of the Find job. Zero means no limit.
+	RetryBudget int
+
 	// ContextDeadlineRetryBackOff controls the error handling on responses.
 	// If not nil, when the request context deadline exceeds, the request
 	// is retried with an exponential backoff interval.
@@ -75,6 +330,12 @@ type Options struct {
 	// If not nil, when the connection times out (based on client timeout), the request
 	// is retried with an exponential backoff interval.
 	TimeoutRetryBackOff *ExponentialBackOffOptions
+
+	// StatusRetryBackOff controls the error handling on responses.
+	// When the HTTP status code of a response is a key, like 429, or is in the status class
+	// of a key, like 5xx, the request is retried with an exponential backoff interval,
+	// waiting for the time requested by the Retry-After header, if present.
+	StatusRetryBackOff map[string]*ExponentialBackOffOptions
 }
 
 type Option func(opts *Options)
@@ -97,6 +358,30 @@ func WithFileType(fileType string) Option {
 	}
 }
 
+func WithMatcher(matcher Matcher) Option {
+	return func(opts *Options) {
+		opts.Matcher = matcher
+	}
+}
+
+func WithSort(key SortKey) Option {
+	return func(opts *Options) {
+		opts.Sort = key
+	}
+}
+
+func WithReverse(reverse bool) Option {
+	return func(opts *Options) {
+		opts.Reverse = reverse
+	}
+}
+
+func WithEntryHandler(handler func(Entry)) Option {
+	return func(opts *Options) {
+		opts.EntryHandler = handler
+	}
+}
+
 func WithRecursive(recursive bool) Option {
 	return func(opts *Options) {
 		opts.Recursive = recursive
@@ -109,6 +394,170 @@ func WithVerbosity(verbosity bool) Option {
 	}
 }
 
+func WithAllowEscape(allowEscape bool) Option {
+	return func(opts *Options) {
+		opts.AllowEscape = allowEscape
+	}
+}
+
+func WithAllowedDomains(allowedDomains []string) Option {
+	return func(opts *Options) {
+		opts.AllowedDomains = allowedDomains
+	}
+}
+
+func WithFollowExternalLinks(followExternalLinks bool) Option {
+	return func(opts *Options) {
+		opts.FollowExternalLinks = followExternalLinks
+	}
+}
+
+func WithIgnoreScheme(ignoreScheme bool) Option {
+	return func(opts *Options) {
+		opts.IgnoreScheme = ignoreScheme
+	}
+}
+
+func WithHostAliases(hostAliases map[string]string) Option {
+	return func(opts *Options) {
+		opts.HostAliases = hostAliases
+	}
+}
+
+func WithCredentials(credentials CredentialProvider) Option {
+	return func(opts *Options) {
+		opts.Credentials = credentials
+	}
+}
+
+func WithHeaders(headers http.Header) Option {
+	return func(opts *Options) {
+		opts.Headers = headers
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(opts *Options) {
+		opts.UserAgent = userAgent
+	}
+}
+
+func WithCookies(cookies []*http.Cookie) Option {
+	return func(opts *Options) {
+		opts.Cookies = cookies
+	}
+}
+
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(opts *Options) {
+		opts.CookieJar = jar
+	}
+}
+
+func WithParallelism(parallelism int) Option {
+	return func(opts *Options) {
+		opts.Parallelism = parallelism
+	}
+}
+
+func WithPerHostParallelism(parallelism int) Option {
+	return func(opts *Options) {
+		opts.PerHostParallelism = parallelism
+	}
+}
+
+func WithDelay(delay time.Duration) Option {
+	return func(opts *Options) {
+		opts.Delay = delay
+	}
+}
+
+func WithRandomDelay(delay time.Duration) Option {
+	return func(opts *Options) {
+		opts.RandomDelay = delay
+	}
+}
+
+func WithRate(rate float64) Option {
+	return func(opts *Options) {
+		opts.Rate = rate
+	}
+}
+
+func WithLimitRules(rules []*LimitRule) Option {
+	return func(opts *Options) {
+		opts.LimitRules = rules
+	}
+}
+
+func WithAdaptiveConcurrency(adaptive bool) Option {
+	return func(opts *Options) {
+		opts.AdaptiveConcurrency = adaptive
+	}
+}
+
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(opts *Options) {
+		opts.CircuitBreakerThreshold = threshold
+		opts.CircuitBreakerCooldown = cooldown
+	}
+}
+
+func WithStorage(storage Storage) Option {
+	return func(opts *Options) {
+		opts.Storage = storage
+	}
+}
+
+func WithSnapshot(snapshot *Snapshot) Option {
+	return func(opts *Options) {
+		opts.Snapshot = snapshot
+	}
+}
+
+func WithCheckpoint(path string, interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.CheckpointFile = path
+		opts.CheckpointInterval = interval
+	}
+}
+
+func WithResume(checkpoint *Checkpoint) Option {
+	return func(opts *Options) {
+		opts.Resume = checkpoint
+	}
+}
+
+func WithMaxRequests(maxRequests int) Option {
+	return func(opts *Options) {
+		opts.MaxRequests = maxRequests
+	}
+}
+
+func WithMaxBytes(maxBytes int64) Option {
+	return func(opts *Options) {
+		opts.MaxBytes = maxBytes
+	}
+}
+
+func WithMaxResults(maxResults int) Option {
+	return func(opts *Options) {
+		opts.MaxResults = maxResults
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *Options) {
+		opts.Timeout = timeout
+	}
+}
+
+func WithRespectRobots(respectRobots bool) Option {
+	return func(opts *Options) {
+		opts.RespectRobots = respectRobots
+	}
+}
+
 func WithAsync(async bool) Option {
 	return func(opts *Options) {
 		opts.Async = async
@@ -127,6 +576,30 @@ func WithMaxBodySize(maxBodySize int) Option {
 	}
 }
 
+func WithStreamListings(streamListings bool) Option {
+	return func(opts *Options) {
+		opts.StreamListings = streamListings
+	}
+}
+
+func WithMaxListingSize(maxListingSize int64) Option {
+	return func(opts *Options) {
+		opts.MaxListingSize = maxListingSize
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(opts *Options) {
+		opts.RetryPolicy = policy
+	}
+}
+
+func WithRetryBudget(budget int) Option {
+	return func(opts *Options) {
+		opts.RetryBudget = budget
+	}
+}
+
 func WithContextDeadlineRetryBackOff(backoff *ExponentialBackOffOptions) Option {
 	return func(opts *Options) {
 		opts.ContextDeadlineRetryBackOff = backoff
@@ -145,6 +618,12 @@ func WithConnTimeoutRetryBackOff(backoff *ExponentialBackOffOptions) Option {
 	}
 }
 
+func WithStatusRetryBackOff(backoffs map[string]*ExponentialBackOffOptions) Option {
+	return func(opts *Options) {
+		opts.StatusRetryBackOff = backoffs
+	}
+}
+
 // NewFind returns a new Find object to find files over HTTP and HTTPS.
 func NewFind(opts ...Option) *Options {
 	o := &Options{}
@@ -167,19 +646,26 @@ func (o *Options) init() {
 		// Set max body size to 100 KB.
 		o.MaxBodySize = 100 * 1024
 	}
+	if o.MaxListingSize == 0 {
+		o.MaxListingSize = DefaultMaxListingSize
+	}
 }
 
 // Validate validates the Find job options and returns an error.
 func (o *Options) Validate() error {
 	// Validate seed URLs.
 	if len(o.SeedURLs) == 0 {
-		return errors.New("no seed URLs specified")
+		return ErrNoSeeds
 	}
 
 	for k, v := range o.SeedURLs {
-		_, err := url.Parse(v)
+		u, err := url.Parse(v)
 		if err != nil {
-			return errors.New("a seed URL is not a valid URL")
+			return &ErrInvalidSeed{URL: v, Err: err}
+		}
+
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &ErrInvalidSeed{URL: v}
 		}
 
 		if !strings.HasSuffix(v, "/") {
@@ -187,24 +673,81 @@ func (o *Options) Validate() error {
 		}
 	}
 
+	// Validate allowed domains.
+	for _, v := range o.AllowedDomains {
+		if _, err := path.Match(v, ""); err != nil {
+			return errors.Wrap(err, "error validating the allowed domains")
+		}
+	}
+
+	// Validate limits.
+	if o.Parallelism < 0 {
+		return ErrNegativeParallelism
+	}
+
+	if o.MaxRequests < 0 || o.MaxBytes < 0 || o.MaxResults < 0 || o.Timeout < 0 {
+		return ErrNegativeBudget
+	}
+
+	for _, v := range o.limitRules() {
+		if err := v.validate(); err != nil {
+			return errors.Wrap(err, "error validating the limits")
+		}
+	}
+
+	// Validate retried status codes.
+	for k := range o.StatusRetryBackOff {
+		if err := ValidateStatusCode(k); err != nil {
+			return errors.Wrap(err, "error validating the retried status codes")
+		}
+	}
+
 	// Validate filename regular expression.
-	if o.FilenameRegexp == "" {
-		return errors.New("no filename regular expression specified")
+	if o.FilenameRegexp == "" && o.Matcher == nil {
+		return ErrNoFilenameRegexp
 	}
 
 	if _, err := regexp.Compile(o.FilenameRegexp); err != nil {
-		return errors.Wrap(err, "error validating the file name expression")
+		return &ErrInvalidFilenameRegexp{Expr: o.FilenameRegexp, Err: err}
 	}
 
 	// Validate file type.
 	if o.FileType == "" {
 		o.FileType = FileTypeReg
-	} else if o.FileType != FileTypeReg && o.FileType != FileTypeDir {
-		return errors.New("file type not supported")
+	}
+
+	reg, dir, err := parseFileType(o.FileType)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case reg && dir:
+		o.FileType = FileTypeReg + "," + FileTypeDir
+	case dir:
+		o.FileType = FileTypeDir
+	default:
+		o.FileType = FileTypeReg
+	}
+
+	// Validate sort key.
+	if o.Sort == "" {
+		o.Sort = SortPath
+	}
+
+	if err := o.Sort.validate(); err != nil {
+		return err
 	}
 
 	o.sanitize()
 
+	// Validate the checkpoint to resume, against the normalized options it's saved with.
+	if o.Resume != nil {
+		if err := o.Resume.validate(o); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -212,13 +755,6 @@ func (o *Options) sanitize() {
 	if strings.HasPrefix(o.FilenameRegexp, "^") && !strings.HasPrefix(o.FilenameRegexp, "^./") && !strings.HasPrefix(o.FilenameRegexp, `^(\./)?`) {
 		o.FilenameRegexp = strings.Replace(o.FilenameRegexp, "^", `^(\./)?`, 1)
 	}
-
-	if o.FileType == FileTypeDir {
-		if strings.HasSuffix(o.FilenameRegexp, "$") && !strings.HasSuffix(o.FilenameRegexp, "/$") {
-			last := strings.LastIndex(o.FilenameRegexp, "$")
-			o.FilenameRegexp = o.FilenameRegexp[:last] + strings.Replace(o.FilenameRegexp[last:], "$", "/?$", 1)
-		}
-	}
 }
 
 func (o *Options) Find() (*Result, error) {
@@ -226,12 +762,5 @@ func (o *Options) Find() (*Result, error) {
 		return nil, errors.Wrap(err, "error validating find options")
 	}
 
-	switch o.FileType {
-	case FileTypeReg:
-		return o.crawlFiles()
-	case FileTypeDir:
-		return o.crawlFolders()
-	default:
-		return o.crawlFiles()
-	}
+	return o.crawl()
 }
@@ -166,7 +166,8 @@ func TestFindDir(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, found)
 	assert.Len(t, found.URLs, len(subdirs))
-	assert.Equal(t, found.BaseNames, subdirs)
+	// The entries are in path order.
+	assert.Equal(t, []string{"bar", "baz", "foo"}, found.BaseNames)
 }
 
 //nolint:dupl
@@ -17,71 +17,99 @@ limitations under the License.
 package find
 
 import (
-	"context"
+	"errors"
 	"log"
-	"os"
+	"time"
 
-	"github.com/cenkalti/backoff"
 	"github.com/gocolly/colly"
-	"github.com/pkg/errors"
-	"golang.org/x/sys/unix"
+)
+
+const (
+	// attemptsKey is the key of the request context storing the number of attempts of the request.
+	attemptsKey = "wfind.attempts"
 
-	utils "github.com/maxgio92/wfind/internal/backoff"
+	// startKey is the key of the request context storing the time of the first attempt of the request.
+	startKey = "wfind.start"
 )
 
-// handleError handles an error received making a colly.Request.
-// It accepts a colly.Response and the error.
-func (o *Options) handleError(response *colly.Response, err error) {
-	switch {
-	// Context deadline is passed.
-	case errors.Is(err, context.DeadlineExceeded):
-		if o.ContextDeadlineRetryBackOff != nil {
-			retryWithExponentialBackoff(response.Request.Retry, o.ContextDeadlineRetryBackOff)
+// errorHandler returns the handler of the errors received making a colly.Request.
+// It accepts a colly.Response and the error, and retries the request as decided by
+// the retry policy, until the retry budget is exhausted. Requests not retried are
+// added to the failures of the results, unless the crawl budget is exhausted and the
+// Find job is stopping.
+func (o *Options) errorHandler(retries *retryBudget, budget *crawlBudget,
+	results *resultSet,
+) func(*colly.Response, error) {
+	policy := o.retryPolicy()
+
+	return func(response *colly.Response, err error) {
+		// Requests canceled as the Find job stopped are not failures.
+		if budget.exhausted() {
+			return
 		}
-	// Request has timed out.
-	case os.IsTimeout(err):
-		if o.TimeoutRetryBackOff != nil {
-			retryWithExponentialBackoff(response.Request.Retry, o.TimeoutRetryBackOff)
+
+		// Requests disallowed by robots.txt are skipped.
+		if errors.Is(err, ErrRobotsDisallowed) {
+			if o.Verbose {
+				log.Printf("skipping %s: %v\n", response.Request.URL, ErrRobotsDisallowed)
+			}
+
+			return
+		}
+
+		failure := newFailure(response, err)
+
+		delay, ok := policy.Retry(failure)
+		if !ok || !retries.take() {
+			if o.Verbose {
+				log.Printf("error: %v\n", err)
+			}
+
+			results.fail(failure)
+
+			return
 		}
-	// Connection has been reset (RST) by the peer.
-	case errors.Is(err, unix.ECONNRESET):
-		if o.ConnResetRetryBackOff != nil {
-			retryWithExponentialBackoff(response.Request.Retry, o.ConnResetRetryBackOff)
+
+		// Retries are made one at a time: the next one is attempted by this handler
+		// only after this one has failed.
+		if !budget.sleep(delay) {
+			return
 		}
-	// Other failures.
-	default:
-		log.Printf("error: %v\n", err)
+
+		//nolint:errcheck
+		response.Request.Retry()
 	}
 }
 
-// retryWithExtponentialBackoff retries with an exponential backoff a function.
-// Exponential backoff can be tuned with options accepted as arguments to the function.
-func retryWithExponentialBackoff(retryF func() error, opts *ExponentialBackOffOptions) {
-	ticker := backoff.NewTicker(
-		utils.NewExponentialBackOff(
-			utils.WithClock(opts.Clock),
-			utils.WithInitialInterval(opts.InitialInterval),
-			utils.WithMaxInterval(opts.MaxInterval),
-			utils.WithMaxElapsedTime(opts.MaxElapsedTime),
-		),
-	)
-
-	var err error
-
-	// Ticks will continue to arrive when the previous retryF is still running,
-	// so operations that take a while to fail could run in quick succession.
-	for range ticker.C {
-		if err = retryF(); err != nil {
-			// Retry.
-			continue
-		}
+// newFailure returns the Failure of the request of a response, counting its attempts.
+func newFailure(response *colly.Response, err error) *Failure {
+	ctx := response.Ctx
+	if ctx == nil {
+		ctx = response.Request.Ctx
+	}
 
-		ticker.Stop()
-		break
+	attempts, _ := ctx.GetAny(attemptsKey).(int)
+	attempts++
+	ctx.Put(attemptsKey, attempts)
+
+	start, ok := ctx.GetAny(startKey).(time.Time)
+	if !ok {
+		start = time.Now()
+		ctx.Put(startKey, start)
+	}
+
+	f := &Failure{
+		URL:        response.Request.URL,
+		Class:      ClassifyError(response.StatusCode, err),
+		StatusCode: response.StatusCode,
+		Err:        err,
+		Attempts:   attempts,
+		Elapsed:    time.Since(start),
 	}
 
-	if err != nil {
-		// Retry has failed.
-		return
+	if response.Headers != nil {
+		f.Header = *response.Headers
 	}
+
+	return f
 }
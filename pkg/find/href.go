@@ -0,0 +1,130 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// hrefKind represents the kind of resource referenced by a link found in a listing.
+type hrefKind int
+
+const (
+	// hrefKindInvalid is a link that cannot be parsed.
+	hrefKindInvalid hrefKind = iota
+
+	// hrefKindQuery is a query-only link, like the autoindex sort links (e.g. ?C=N;O=D).
+	hrefKindQuery
+
+	// hrefKindFragment is a fragment-only link (e.g. #top).
+	hrefKindFragment
+
+	// hrefKindScheme is a link with a non-HTTP scheme (e.g. mailto: or javascript:).
+	hrefKindScheme
+
+	// hrefKindExternal is a link to a host different from the one of the listing.
+	hrefKindExternal
+
+	// hrefKindParent is a link to the listing itself or to one of its ancestors.
+	hrefKindParent
+
+	// hrefKindFolder is a link to a folder below the listing.
+	hrefKindFolder
+
+	// hrefKindFile is a link to a file below the listing.
+	hrefKindFile
+)
+
+// classifyHref returns the absolute URL of href, found in the listing at base,
+// and the kind of the resource it references.
+// The URL is nil for query-only, fragment-only, non-HTTP and invalid references.
+func classifyHref(base *url.URL, href string) (*url.URL, hrefKind) {
+	href = strings.TrimSpace(href)
+
+	switch {
+	case href == "":
+		return nil, hrefKindInvalid
+	case strings.HasPrefix(href, "?"):
+		return nil, hrefKindQuery
+	case strings.HasPrefix(href, "#"):
+		return nil, hrefKindFragment
+	}
+
+	ref, err := url.Parse(href)
+	if err != nil {
+		return nil, hrefKindInvalid
+	}
+
+	if ref.Scheme != "" && ref.Scheme != "http" && ref.Scheme != "https" {
+		return nil, hrefKindScheme
+	}
+
+	abs := base.ResolveReference(ref)
+
+	if !strings.EqualFold(abs.Host, base.Host) {
+		return abs, hrefKindExternal
+	}
+
+	if isAncestorPath(abs.Path, base.Path) {
+		return abs, hrefKindParent
+	}
+
+	return abs, urlKind(abs)
+}
+
+// urlKind returns whether the absolute URL u references a folder or a file.
+func urlKind(u *url.URL) hrefKind {
+	if strings.HasSuffix(u.Path, RootDir) {
+		return hrefKindFolder
+	}
+
+	return hrefKindFile
+}
+
+// isAncestorPath returns whether the path p is the same as, or an ancestor of, the path of.
+func isAncestorPath(p, of string) bool {
+	if !strings.HasPrefix(of, p) {
+		return false
+	}
+
+	return len(of) == len(p) || strings.HasSuffix(p, RootDir) || of[len(p)] == '/'
+}
+
+// decodeHref returns the percent-decoded href.
+// Hrefs that don't decode to valid UTF-8 are returned as they are.
+func decodeHref(href string) string {
+	decoded, err := url.PathUnescape(href)
+	if err != nil || !utf8.ValidString(decoded) {
+		return href
+	}
+
+	return decoded
+}
+
+// entryName returns the percent-decoded path base of the absolute URL u.
+// Names that don't decode to valid UTF-8 are returned percent-encoded.
+func entryName(u *url.URL) string {
+	name := path.Base(u.Path)
+	if !utf8.ValidString(name) {
+		return path.Base(u.EscapedPath())
+	}
+
+	return name
+}
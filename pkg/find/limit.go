@@ -0,0 +1,389 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maxgio92/wfind/internal/network"
+)
+
+// LimitRule limits the requests to the hosts matching a pattern.
+// Limits apply to each host separately.
+type LimitRule struct {
+	// Host is the host, or wildcard pattern like *.kernel.org, the rule applies to.
+	Host string
+
+	// Parallelism is the maximum number of concurrent requests to each host. Zero means no limit.
+	Parallelism int
+
+	// Delay is the time to wait after each request to a host, before making the next one.
+	Delay time.Duration
+
+	// RandomDelay is the maximum random time added to the Delay.
+	RandomDelay time.Duration
+
+	// Rate is the maximum number of requests per second to each host. Zero means no limit.
+	Rate float64
+}
+
+// ParseLimitRule parses a limit rule in the form HOST=KEY=VALUE,..., where the keys
+// are parallelism, delay, random-delay and rate, like *.kernel.org=parallelism=2,rate=0.5.
+func ParseLimitRule(s string) (*LimitRule, error) {
+	host, limits, ok := strings.Cut(s, "=")
+	if !ok || host == "" || limits == "" {
+		//nolint:goerr113
+		return nil, fmt.Errorf("limit rule %q is not in the form HOST=KEY=VALUE,...", s)
+	}
+
+	rule := &LimitRule{Host: host}
+
+	for _, v := range strings.Split(limits, ",") {
+		key, value, _ := strings.Cut(v, "=")
+
+		var err error
+
+		switch strings.TrimSpace(key) {
+		case "parallelism":
+			rule.Parallelism, err = strconv.Atoi(value)
+		case "delay":
+			rule.Delay, err = time.ParseDuration(value)
+		case "random-delay":
+			rule.RandomDelay, err = time.ParseDuration(value)
+		case "rate":
+			rule.Rate, err = strconv.ParseFloat(value, 64)
+		default:
+			//nolint:goerr113
+			return nil, fmt.Errorf("unknown limit %q in limit rule %q", key, s)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit %q in limit rule %q: %w", key, s, err)
+		}
+	}
+
+	return rule, rule.validate()
+}
+
+func (r *LimitRule) validate() error {
+	if _, err := path.Match(r.Host, ""); err != nil {
+		return fmt.Errorf("invalid host pattern %q: %w", r.Host, err)
+	}
+
+	if r.Parallelism < 0 || r.Delay < 0 || r.RandomDelay < 0 || r.Rate < 0 {
+		//nolint:goerr113
+		return fmt.Errorf("negative limit for host %q", r.Host)
+	}
+
+	return nil
+}
+
+// hostLimiter limits the requests to a host.
+type hostLimiter struct {
+	rule     *LimitRule
+	slots    chan struct{}
+	adaptive *aimd
+
+	mu         sync.Mutex
+	next       time.Time
+	crawlDelay time.Duration
+}
+
+// setCrawlDelay sets the minimum interval between the starts of the requests to the host.
+func (h *hostLimiter) setCrawlDelay(delay time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.crawlDelay = delay
+}
+
+func newHostLimiter(host string, rule *LimitRule, adaptive, verbose bool) *hostLimiter {
+	h := &hostLimiter{rule: rule}
+
+	switch {
+	case adaptive:
+		h.adaptive = newAIMD(host, rule.Parallelism, verbose)
+	case rule.Parallelism > 0:
+		h.slots = make(chan struct{}, rule.Parallelism)
+	}
+
+	return h
+}
+
+// take waits for a slot of the request to the host.
+func (h *hostLimiter) take(ctx context.Context) error {
+	if h.adaptive != nil {
+		return h.adaptive.acquire(ctx)
+	}
+
+	return take(ctx, h.slots)
+}
+
+// give releases the slot of a request to the host.
+func (h *hostLimiter) give() {
+	if h.adaptive != nil {
+		h.adaptive.release()
+
+		return
+	}
+
+	give(h.slots)
+}
+
+// observe records the outcome of a request to the host, adapting its concurrency.
+func (h *hostLimiter) observe(latency time.Duration, status int, err error) {
+	if h.adaptive != nil {
+		h.adaptive.observe(latency, status, err)
+	}
+}
+
+// wait waits for the rate of the requests to the host, for its crawl delay, and for the
+// politeness delay after the previous request, unless ctx is done meanwhile.
+func (h *hostLimiter) wait(ctx context.Context) error {
+	h.mu.Lock()
+
+	interval := h.crawlDelay
+	if h.rule.Rate > 0 {
+		if i := time.Duration(float64(time.Second) / h.rule.Rate); i > interval {
+			interval = i
+		}
+	}
+
+	now := time.Now()
+
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+
+	if interval > 0 {
+		h.next = start.Add(interval)
+	}
+
+	h.mu.Unlock()
+
+	return sleep(ctx, start.Sub(now))
+}
+
+// delay delays the next request to the host by the politeness delay, after a request to the host.
+func (h *hostLimiter) delay() {
+	delay := h.rule.Delay
+	if h.rule.RandomDelay > 0 {
+		//nolint:gosec
+		delay += time.Duration(rand.Int63n(int64(h.rule.RandomDelay)))
+	}
+
+	if delay <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if next := time.Now().Add(delay); next.After(h.next) {
+		h.next = next
+	}
+}
+
+// limiter limits the requests of a Find job overall and to each host.
+type limiter struct {
+	global   chan struct{}
+	rules    []*LimitRule
+	adaptive bool
+	verbose  bool
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter
+}
+
+func newLimiter(parallelism int, rules []*LimitRule, adaptive, verbose bool) *limiter {
+	l := &limiter{rules: rules, adaptive: adaptive, verbose: verbose, hosts: map[string]*hostLimiter{}}
+	if parallelism > 0 {
+		l.global = make(chan struct{}, parallelism)
+	}
+
+	return l
+}
+
+// host returns the limiter of the host of the URL u, with the first rule matching it.
+func (l *limiter) host(u *url.URL) *hostLimiter {
+	key := strings.ToLower(u.Host)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if h, ok := l.hosts[key]; ok {
+		return h
+	}
+
+	rule := &LimitRule{}
+
+	for _, v := range l.rules {
+		if network.HostMatches(v.Host, u) {
+			rule = v
+
+			break
+		}
+	}
+
+	h := newHostLimiter(key, rule, l.adaptive, l.verbose)
+	l.hosts[key] = h
+
+	return h
+}
+
+// acquire waits for a slot of the request to the host of the URL u, and returns
+// its host limiter and the function to release it, after the politeness delay
+// if the request has been made.
+func (l *limiter) acquire(ctx context.Context, u *url.URL) (*hostLimiter, func(made bool), error) {
+	h := l.host(u)
+
+	if err := h.take(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	if err := h.wait(ctx); err != nil {
+		h.give()
+
+		return nil, nil, err
+	}
+
+	if err := take(ctx, l.global); err != nil {
+		h.give()
+
+		return nil, nil, err
+	}
+
+	var once sync.Once
+
+	return h, func(made bool) {
+		once.Do(func() {
+			give(l.global)
+
+			if made {
+				h.delay()
+			}
+
+			h.give()
+		})
+	}, nil
+}
+
+func take(ctx context.Context, slots chan struct{}) error {
+	if slots == nil {
+		return nil
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func give(slots chan struct{}) {
+	if slots != nil {
+		<-slots
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limitTransport is an HTTP transport limiting the requests. A request holds its
+// slot until its response body is closed. Being in the transport, limits apply
+// to each redirect too. The outcome of the requests adapts the concurrency of
+// the hosts, if adaptive.
+type limitTransport struct {
+	limiter *limiter
+	next    http.RoundTripper
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h, release, err := t.limiter.acquire(req.Context(), req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		h.observe(time.Since(start), 0, err)
+		release(!errors.Is(err, ErrCircuitOpen))
+
+		return nil, err
+	}
+
+	h.observe(time.Since(start), res.StatusCode, nil)
+
+	res.Body = &releaseReadCloser{ReadCloser: res.Body, release: func() { release(true) }}
+
+	return res, nil
+}
+
+// releaseReadCloser releases the slot of a request when its response body is closed.
+type releaseReadCloser struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+
+	return err
+}
+
+// limitRules returns the limit rules of the Find job, followed by the default one.
+func (o *Options) limitRules() []*LimitRule {
+	rules := make([]*LimitRule, 0, len(o.LimitRules)+1)
+	rules = append(rules, o.LimitRules...)
+
+	return append(rules, &LimitRule{
+		Host:        "*",
+		Parallelism: o.PerHostParallelism,
+		Delay:       o.Delay,
+		RandomDelay: o.RandomDelay,
+		Rate:        o.Rate,
+	})
+}
@@ -0,0 +1,159 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+// concurrencyRecorder is an HTTP handler serving a listing of folders, each with a file,
+// and recording the maximum number of concurrent requests.
+type concurrencyRecorder struct {
+	folders int
+	latency time.Duration
+
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func (c *concurrencyRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	c.current++
+	if c.current > c.max {
+		c.max = c.current
+	}
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.current--
+		c.mu.Unlock()
+	}()
+
+	time.Sleep(c.latency)
+
+	if r.URL.Path == listingPath {
+		for i := 0; i < c.folders; i++ {
+			fmt.Fprintf(w, `<a href="d%d/">d%d/</a>`, i, i)
+		}
+
+		return
+	}
+
+	fmt.Fprintf(w, `<a href="file">file</a>`)
+}
+
+func TestFindFileLimits(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name           string
+		options        []find.Option
+		maxConcurrency int
+		minDuration    time.Duration
+	}{
+		{"per host parallelism", []find.Option{find.WithPerHostParallelism(2)}, 2, 0},
+		{"parallelism", []find.Option{find.WithParallelism(1), find.WithPerHostParallelism(4)}, 1, 0},
+		{"limit rule", []find.Option{
+			find.WithPerHostParallelism(4),
+			find.WithLimitRules([]*find.LimitRule{{Host: "127.0.0.*", Parallelism: 1}}),
+		}, 1, 0},
+		{"adaptive", []find.Option{find.WithAdaptiveConcurrency(true), find.WithPerHostParallelism(3)}, 3, 0},
+		{"rate", []find.Option{find.WithRate(40)}, 0, 10 * time.Second / 40},
+		{"delay", []find.Option{find.WithPerHostParallelism(1), find.WithDelay(20 * time.Millisecond)}, 1, 10 * 20 * time.Millisecond},
+	} {
+		recorder := &concurrencyRecorder{folders: 10, latency: 10 * time.Millisecond}
+
+		server := httptest.NewServer(recorder)
+		defer server.Close()
+
+		options := append([]find.Option{
+			find.WithSeedURLs([]string{server.URL + listingPath}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithAsync(true),
+		}, tc.options...)
+
+		start := time.Now()
+		found, err := find.NewFind(options...).Find()
+
+		assert.Nil(t, err, tc.name)
+		assert.NotNil(t, found, tc.name)
+		assert.Len(t, found.URLs, 10, tc.name)
+
+		if tc.maxConcurrency > 0 {
+			assert.LessOrEqual(t, recorder.max, tc.maxConcurrency, tc.name)
+		}
+
+		assert.GreaterOrEqual(t, time.Since(start), tc.minDuration, tc.name)
+	}
+}
+
+func TestParseLimitRule(t *testing.T) {
+	t.Parallel()
+
+	rule, err := find.ParseLimitRule("*.kernel.org=parallelism=2,delay=1s,random-delay=500ms,rate=0.5")
+
+	assert.Nil(t, err)
+	assert.Equal(t, &find.LimitRule{
+		Host:        "*.kernel.org",
+		Parallelism: 2,
+		Delay:       time.Second,
+		RandomDelay: 500 * time.Millisecond,
+		Rate:        0.5,
+	}, rule)
+
+	for _, v := range []string{"*.kernel.org", "*.kernel.org=speed=1", "*.kernel.org=parallelism=-1", "[=rate=1"} {
+		_, err = find.ParseLimitRule(v)
+
+		assert.NotNil(t, err, v)
+	}
+}
+
+func TestFindFileDelayTimeout(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(&concurrencyRecorder{folders: 10})
+	defer server.Close()
+
+	start := time.Now()
+	found, err := find.NewFind(
+		find.WithSeedURLs([]string{server.URL + listingPath}),
+		find.WithFilenameRegexp(`.+`),
+		find.WithFileType(find.FileTypeReg),
+		find.WithRecursive(true),
+		find.WithAsync(true),
+		find.WithPerHostParallelism(1),
+		find.WithDelay(time.Minute),
+		find.WithTimeout(200*time.Millisecond),
+	).Find()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, found)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// listingTimeLayouts are the layouts of the modification times in the listings of Apache and nginx.
+var listingTimeLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"02-Jan-2006 15:04",
+	"02-Jan-2006 15:04:05",
+}
+
+// listingSizeUnits are the multipliers of the human-readable sizes in the listings.
+var listingSizeUnits = map[byte]float64{
+	'K': 1 << 10,
+	'M': 1 << 20,
+	'G': 1 << 30,
+	'T': 1 << 40,
+}
+
+// listingColumns returns the text of the columns following the link n in its listing,
+// either the cells of its table row or the text of its line.
+func listingColumns(n *xhtml.Node) string {
+	if p := n.Parent; p != nil && p.Type == xhtml.ElementNode && p.Data == "td" {
+		columns := []string{}
+
+		for c := p.NextSibling; c != nil; c = c.NextSibling {
+			if c.Type == xhtml.ElementNode && c.Data == "td" {
+				columns = append(columns, nodeText(c))
+			}
+		}
+
+		return strings.Join(columns, " ")
+	}
+
+	if c := n.NextSibling; c != nil && c.Type == xhtml.TextNode {
+		line, _, _ := strings.Cut(c.Data, "\n")
+
+		return line
+	}
+
+	return ""
+}
+
+// nodeText returns the text of the node n.
+func nodeText(n *xhtml.Node) string {
+	if n.Type == xhtml.TextNode {
+		return n.Data
+	}
+
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(nodeText(c))
+	}
+
+	return b.String()
+}
+
+// parseListingColumns returns the modification time and the size found in the columns of a listing,
+// zero if missing.
+func parseListingColumns(columns string) (time.Time, int64) {
+	fields := strings.Fields(columns)
+
+	for i := 0; i+1 < len(fields); i++ {
+		for _, layout := range listingTimeLayouts {
+			mtime, err := time.Parse(layout, fields[i]+" "+fields[i+1])
+			if err != nil {
+				continue
+			}
+
+			// The size follows the modification time.
+			var size int64
+			if i+2 < len(fields) {
+				size = parseListingSize(fields[i+2])
+			}
+
+			return mtime, size
+		}
+	}
+
+	return time.Time{}, 0
+}
+
+// parseListingSize returns the size in bytes s, either in bytes or human-readable, zero if invalid.
+func parseListingSize(s string) int64 {
+	multiplier := 1.0
+	if m, ok := listingSizeUnits[strings.ToUpper(s[len(s)-1:])[0]]; ok {
+		multiplier = m
+		s = s[:len(s)-1]
+	}
+
+	if s == "" || !isDigit(s[0]) {
+		return 0
+	}
+
+	size, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+
+	return int64(size * multiplier)
+}
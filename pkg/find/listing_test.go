@@ -0,0 +1,219 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vitorsalgado/mocha/v3"
+	"github.com/vitorsalgado/mocha/v3/expect"
+	"github.com/vitorsalgado/mocha/v3/reply"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+const listingPath = "/pub/linux/"
+
+var listingFixtures = []string{"apache.html", "nginx.html"}
+
+func initListingWebServer(t *testing.T, fixture string) *mocha.Mocha {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := mocha.New(t).CloseOnCleanup(t)
+	m.Start()
+
+	m.AddMocks(
+		mocha.Get(expect.URLPath(listingPath)).
+			Reply(reply.OK().BodyString(string(body))))
+
+	return m
+}
+
+func TestFindFileListing(t *testing.T) {
+	t.Parallel()
+
+	for _, fixture := range listingFixtures {
+		fixture := fixture
+
+		t.Run(fixture, func(t *testing.T) {
+			t.Parallel()
+
+			m := initListingWebServer(t, fixture)
+
+			finder := find.NewFind(
+				find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+				find.WithFilenameRegexp(`.+`),
+				find.WithFileType(find.FileTypeReg),
+				find.WithRecursive(false),
+			)
+
+			found, err := finder.Find()
+
+			assert.Nil(t, err)
+			assert.NotNil(t, found)
+			assert.Equal(t, []string{"README", "sha256sums.asc"}, found.BaseNames)
+		})
+	}
+}
+
+func TestFindDirListing(t *testing.T) {
+	t.Parallel()
+
+	for _, fixture := range listingFixtures {
+		fixture := fixture
+
+		t.Run(fixture, func(t *testing.T) {
+			t.Parallel()
+
+			m := initListingWebServer(t, fixture)
+
+			finder := find.NewFind(
+				find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+				find.WithFilenameRegexp(`.+`),
+				find.WithFileType(find.FileTypeDir),
+				find.WithRecursive(false),
+			)
+
+			found, err := finder.Find()
+
+			assert.Nil(t, err)
+			assert.NotNil(t, found)
+			assert.Equal(t, []string{"kernel", "utils"}, found.BaseNames)
+		})
+	}
+}
+
+func TestFindDirDuplicates(t *testing.T) {
+	t.Parallel()
+
+	m := mocha.New(t).CloseOnCleanup(t)
+	m.Start()
+
+	m.AddMocks(
+		mocha.Get(expect.URLPath(listingPath)).
+			Reply(reply.OK().BodyString(`<a href="./foo/">foo/</a><a href="foo/">foo/</a><a href="foo//">foo/</a>`+
+				`<a href="%66oo/">foo/</a><a href="bar/../foo/">foo/</a>`)),
+		mocha.Get(expect.URLPath(listingPath+"foo/")).
+			Reply(reply.OK().BodyString(`<a href="../">../</a>`)))
+
+	finder := find.NewFind(
+		find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+		find.WithFilenameRegexp(`.+`),
+		find.WithFileType(find.FileTypeDir),
+		find.WithRecursive(true),
+	)
+
+	found, err := finder.Find()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, found)
+	assert.Equal(t, []string{"foo"}, found.BaseNames)
+	assert.Equal(t, []string{fmt.Sprintf("%s%sfoo/", m.URL(), listingPath)}, found.URLs)
+	m.AssertHits(t, 2)
+}
+
+func TestFindFileIndexFiles(t *testing.T) {
+	t.Parallel()
+
+	for fileType, expected := range map[string][]string{
+		find.FileTypeReg: {"index.html", "sub/index.html"},
+		"f,d":            {"index.html", "sub/", "sub/index.html"},
+	} {
+		m := mocha.New(t).CloseOnCleanup(t)
+		m.Start()
+
+		m.AddMocks(
+			mocha.Get(expect.URLPath(listingPath)).
+				Reply(reply.OK().BodyString(`<a href="index.html">index.html</a><a href="sub/">sub/</a>`)),
+			mocha.Get(expect.URLPath(listingPath+"sub/")).
+				Reply(reply.OK().BodyString(`<a href="index.html">index.html</a>`)))
+
+		found, err := find.NewFind(
+			find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(fileType),
+			find.WithRecursive(true),
+		).Find()
+
+		assert.Nil(t, err, fileType)
+		assert.NotNil(t, found, fileType)
+
+		// The index files are files of their own, distinct from their folders.
+		urls := []string{}
+		for _, v := range expected {
+			urls = append(urls, fmt.Sprintf("%s%s%s", m.URL(), listingPath, v))
+		}
+
+		assert.ElementsMatch(t, urls, found.URLs, fileType)
+	}
+}
+
+func TestFindFileEncodedNames(t *testing.T) {
+	t.Parallel()
+
+	for fixture, hrefs := range map[string][]string{
+		"escaped.html": {"caf%C3%A9.txt", "foo%20bar.rpm"},
+		"utf8.html":    {"café.txt", "foo bar.rpm"},
+		"latin1.html":  {"café.txt", "foo bar.rpm"},
+	} {
+		fixture, hrefs := fixture, hrefs
+
+		t.Run(fixture, func(t *testing.T) {
+			t.Parallel()
+
+			body, err := os.ReadFile(filepath.Join("testdata", fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := mocha.New(t).CloseOnCleanup(t)
+			m.Start()
+
+			// Like nginx autoindex, do not declare the charset.
+			m.AddMocks(
+				mocha.Get(expect.URLPath(listingPath)).
+					Reply(reply.OK().Header("Content-Type", "text/html").Body(body)))
+
+			finder := find.NewFind(
+				find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+				find.WithFilenameRegexp(`^(foo bar|café)\.`),
+				find.WithFileType(find.FileTypeReg),
+				find.WithRecursive(false),
+			)
+
+			found, err := finder.Find()
+
+			assert.Nil(t, err)
+			assert.NotNil(t, found)
+			assert.Equal(t, []string{"café.txt", "foo bar.rpm"}, found.BaseNames)
+			assert.Len(t, found.Entries, len(hrefs))
+
+			for i, v := range found.Entries {
+				assert.Equal(t, hrefs[i], v.Href)
+			}
+		})
+	}
+}
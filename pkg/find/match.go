@@ -0,0 +1,139 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Matcher is the match stage of the Find job, selecting the entries of the Result among the ones
+// of the types searched.
+type Matcher interface {
+	Match(entry Entry) bool
+}
+
+// MatcherFunc is an adapter to use ordinary functions as Matcher.
+type MatcherFunc func(entry Entry) bool
+
+// Match calls f(entry).
+func (f MatcherFunc) Match(entry Entry) bool {
+	return f(entry)
+}
+
+// regexpMatcher matches the entries whose href and name match the file name expression.
+type regexpMatcher struct {
+	file, dir namePattern
+}
+
+// namePattern matches the entries of a type.
+type namePattern struct {
+	// href matches the href of the entries, which can be either relative or absolute.
+	// It's anchored to the start of a path segment wherever the name is anchored to its start.
+	href *regexp.Regexp
+
+	// name matches the names of the entries.
+	name *regexp.Regexp
+}
+
+func newNamePattern(expr string) (namePattern, error) {
+	name, err := regexp.Compile(expr)
+	if err != nil {
+		return namePattern{}, err
+	}
+
+	hrefExpr := expr
+	if strings.HasPrefix(expr, "^") {
+		hrefExpr = "(?:^|/)" + strings.TrimPrefix(expr, "^")
+	}
+
+	href, err := regexp.Compile(hrefExpr)
+	if err != nil {
+		return namePattern{}, err
+	}
+
+	return namePattern{href: href, name: name}, nil
+}
+
+// newRegexpMatcher returns the Matcher of the file name expression expr.
+func newRegexpMatcher(expr string) (*regexpMatcher, error) {
+	file, err := newNamePattern(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Folders match with or without the trailing slash.
+	if strings.HasSuffix(expr, "$") && !strings.HasSuffix(expr, "/$") {
+		expr = strings.TrimSuffix(expr, "$") + "/?$"
+	}
+
+	dir, err := newNamePattern(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &regexpMatcher{file: file, dir: dir}, nil
+}
+
+// Match returns whether the href of entry, and its name, match the expression.
+func (m *regexpMatcher) Match(entry Entry) bool {
+	p, name := m.file, entry.Name
+	if entry.Type == FileTypeDir {
+		p, name = m.dir, entry.Name+"/"
+	}
+
+	return p.href.MatchString(decodeHref(entry.Href)) && p.name.MatchString(name)
+}
+
+// matcher returns the match stage of the Find job, which is the Matcher option if set,
+// otherwise matches the file name expression.
+func (o *Options) matcher() (Matcher, error) {
+	if o.Matcher != nil {
+		return o.Matcher, nil
+	}
+
+	return newRegexpMatcher(o.FilenameRegexp)
+}
+
+// parseFileType returns whether the comma-separated file types s include regular files and folders.
+func parseFileType(s string) (reg, dir bool, err error) {
+	for _, v := range strings.Split(s, ",") {
+		switch strings.TrimSpace(v) {
+		case FileTypeReg:
+			reg = true
+		case FileTypeDir:
+			dir = true
+		default:
+			return false, false, ErrUnsupportedFileType
+		}
+	}
+
+	return reg, dir, nil
+}
+
+// newEntry returns the Entry of the link href to the absolute URL u, of a folder if dir.
+func newEntry(u *url.URL, href string, dir bool) Entry {
+	c := canonicalURL(u)
+
+	entry := Entry{Name: entryName(c), URL: c.String(), Href: href, Type: FileTypeReg}
+	if dir {
+		entry.Type = FileTypeDir
+	}
+
+	return entry
+}
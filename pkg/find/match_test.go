@@ -0,0 +1,49 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamePattern(t *testing.T) {
+	t.Parallel()
+
+	o := &Options{FilenameRegexp: "^foo"}
+	o.sanitize()
+
+	p, err := newNamePattern(o.FilenameRegexp)
+	assert.Nil(t, err)
+
+	// The href and the name patterns are anchored alike.
+	for _, tc := range []struct {
+		href, name string
+		expected   bool
+	}{
+		{"foo", "foo", true},
+		{"./foo", "foo", true},
+		{"/pub/foo", "foo", true},
+		{"http://example.org/pub/foo.tar.gz", "foo.tar.gz", true},
+		{"barfoo", "barfoo", false},
+		{"/pub/barfoo", "barfoo", false},
+	} {
+		assert.Equal(t, tc.expected, p.href.MatchString(tc.href), tc.href)
+		assert.Equal(t, tc.expected, p.name.MatchString(tc.name), tc.name)
+	}
+}
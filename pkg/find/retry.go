@@ -17,11 +17,31 @@ limitations under the License.
 package find
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff"
 )
 
+// DefaultJitter is the default randomization factor of the retry delays.
+const DefaultJitter = backoff.DefaultRandomizationFactor
+
+// maxRetryAfter is the maximum delay requested by the Retry-After header honored,
+// even with no maximum elapsed time.
+const maxRetryAfter = 5 * time.Minute
+
+// statusPattern matches the retried HTTP status codes, like 429, and classes, like 5xx.
+var statusPattern = regexp.MustCompile(`^[1-5]([0-9]{2}|[xX]{2})$`)
+
 var DefaultExponentialBackOffOptions = &ExponentialBackOffOptions{
 	InitialInterval: 2 * time.Second,
 	MaxInterval:     10 * time.Second,
@@ -71,3 +91,274 @@ func NewExponentialBackOffOptions(opts ...ExponentialBackoffOption) *Exponential
 
 	return b
 }
+
+// retryAfter returns the delay requested by the Retry-After header, in seconds or as HTTP-date,
+// up to maxRetryAfter.
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+
+	if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else {
+		date, err := http.ParseTime(value)
+		if err != nil {
+			return 0, false
+		}
+
+		delay = date.Sub(now)
+	}
+
+	switch {
+	case delay < 0:
+		return 0, true
+	case delay > maxRetryAfter:
+		return maxRetryAfter, true
+	default:
+		return delay, true
+	}
+}
+
+// Failure represents a failed attempt of a request.
+type Failure struct {
+	// URL is the URL of the request.
+	URL *url.URL
+
+	// Class is the class of the failure.
+	Class ErrorClass
+
+	// StatusCode is the HTTP status code of the response, if any.
+	StatusCode int
+
+	// Header is the header of the response, if any.
+	Header http.Header
+
+	// Err is the error of the request.
+	Err error
+
+	// Attempts is the number of attempts of the request made, including the failed one.
+	Attempts int
+
+	// Elapsed is the time elapsed since the first failure of the request.
+	Elapsed time.Duration
+}
+
+// FetchError returns the FetchError of the failed request.
+func (f *Failure) FetchError() *FetchError {
+	e := &FetchError{
+		Status:   f.StatusCode,
+		Attempts: f.Attempts,
+		Class:    f.Class,
+		Err:      f.Err,
+	}
+
+	if f.URL != nil {
+		e.URL = f.URL.String()
+	}
+
+	return e
+}
+
+// RetryPolicy decides whether to retry failed requests.
+type RetryPolicy interface {
+	// Retry returns whether the request of the Failure f should be retried,
+	// and the delay to wait before retrying it.
+	Retry(f *Failure) (time.Duration, bool)
+}
+
+// RetryPolicies retry the failed requests with the first policy retrying them.
+type RetryPolicies []RetryPolicy
+
+func (p RetryPolicies) Retry(f *Failure) (time.Duration, bool) {
+	for _, v := range p {
+		if v == nil {
+			continue
+		}
+
+		if delay, ok := v.Retry(f); ok {
+			return delay, true
+		}
+	}
+
+	return 0, false
+}
+
+// Classifier reports whether a Failure is of a kind of failures.
+type Classifier func(f *Failure) bool
+
+// IsClass returns a Classifier of the failures of the classes.
+func IsClass(classes ...ErrorClass) Classifier {
+	return func(f *Failure) bool {
+		for _, v := range classes {
+			if f.Class == v {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// IsStatus returns a Classifier of the failures with the HTTP status codes, like 429,
+// or in the status classes, like 5xx.
+func IsStatus(codes ...string) Classifier {
+	return func(f *Failure) bool {
+		if f.StatusCode == 0 {
+			return false
+		}
+
+		code := strconv.Itoa(f.StatusCode)
+
+		for _, v := range codes {
+			if v == code || strings.EqualFold(v, code[:1]+"xx") {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// ValidateStatusCode returns an error if code is not an HTTP status code, like 429, or class, like 5xx.
+func ValidateStatusCode(code string) error {
+	if !statusPattern.MatchString(code) {
+		//nolint:goerr113
+		return fmt.Errorf("invalid HTTP status code %q", code)
+	}
+
+	return nil
+}
+
+// AnyOf returns a Classifier of the failures of any of the classifiers.
+func AnyOf(classifiers ...Classifier) Classifier {
+	return func(f *Failure) bool {
+		for _, v := range classifiers {
+			if v(f) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// ExponentialBackOffPolicy retries the failures of a Classifier with an exponential backoff interval,
+// waiting for the time requested by the Retry-After header, if present.
+type ExponentialBackOffPolicy struct {
+	// Classifier selects the retried failures.
+	Classifier Classifier
+
+	// BackOff is the exponential backoff of the retries.
+	BackOff *ExponentialBackOffOptions
+
+	// MaxAttempts is the maximum number of attempts of each request. Zero means no limit.
+	MaxAttempts int
+
+	// Jitter is the randomization factor of the delays, between 0 and 1.
+	Jitter float64
+}
+
+// NewExponentialBackOffPolicy returns a new ExponentialBackOffPolicy with the default jitter.
+func NewExponentialBackOffPolicy(classifier Classifier, backoff *ExponentialBackOffOptions,
+	maxAttempts int,
+) *ExponentialBackOffPolicy {
+	return &ExponentialBackOffPolicy{
+		Classifier:  classifier,
+		BackOff:     backoff,
+		MaxAttempts: maxAttempts,
+		Jitter:      DefaultJitter,
+	}
+}
+
+func (p *ExponentialBackOffPolicy) Retry(f *Failure) (time.Duration, bool) {
+	if p.Classifier == nil || p.BackOff == nil || !p.Classifier(f) {
+		return 0, false
+	}
+
+	if p.MaxAttempts > 0 && f.Attempts >= p.MaxAttempts {
+		return 0, false
+	}
+
+	var clock backoff.Clock = backoff.SystemClock
+	if p.BackOff.Clock != nil {
+		clock = p.BackOff.Clock
+	}
+
+	delay, ok := retryAfter(f.Header, clock.Now())
+	if !ok {
+		delay = p.interval(f.Attempts)
+	}
+
+	if p.BackOff.MaxElapsedTime > 0 && f.Elapsed+delay > p.BackOff.MaxElapsedTime {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+// interval returns the randomized backoff interval after the attempt.
+func (p *ExponentialBackOffPolicy) interval(attempt int) time.Duration {
+	interval := float64(p.BackOff.InitialInterval) * math.Pow(backoff.DefaultMultiplier, float64(attempt-1))
+	if p.BackOff.MaxInterval > 0 && interval > float64(p.BackOff.MaxInterval) {
+		interval = float64(p.BackOff.MaxInterval)
+	}
+
+	//nolint:gosec
+	interval *= 1 + p.Jitter*(2*rand.Float64()-1)
+
+	return time.Duration(interval)
+}
+
+// retryPolicy returns the RetryPolicy of the Find job, followed by the policies
+// of the backoff options.
+func (o *Options) retryPolicy() RetryPolicy {
+	policies := RetryPolicies{o.RetryPolicy}
+
+	for _, v := range []struct {
+		backoff *ExponentialBackOffOptions
+		class   ErrorClass
+	}{
+		{o.ContextDeadlineRetryBackOff, ErrorClassContextDeadline},
+		{o.TimeoutRetryBackOff, ErrorClassTimeout},
+		{o.ConnResetRetryBackOff, ErrorClassConnReset},
+	} {
+		if v.backoff != nil {
+			policies = append(policies, NewExponentialBackOffPolicy(IsClass(v.class), v.backoff, 0))
+		}
+	}
+
+	// Status codes are preferred to status classes.
+	codes := make([]string, 0, len(o.StatusRetryBackOff))
+	for k := range o.StatusRetryBackOff {
+		codes = append(codes, k)
+	}
+
+	sort.Slice(codes, func(a, b int) bool {
+		return strings.ToLower(codes[a]) < strings.ToLower(codes[b])
+	})
+
+	for _, v := range codes {
+		policies = append(policies, NewExponentialBackOffPolicy(IsStatus(v), o.StatusRetryBackOff[v], 0))
+	}
+
+	return policies
+}
+
+// retryBudget limits the retries of a Find job.
+type retryBudget struct {
+	remaining int64
+	limited   bool
+}
+
+func newRetryBudget(retries int) *retryBudget {
+	return &retryBudget{remaining: int64(retries), limited: retries > 0}
+}
+
+// take returns whether a retry is available, consuming it.
+func (b *retryBudget) take() bool {
+	return !b.limited || atomic.AddInt64(&b.remaining, -1) >= 0
+}
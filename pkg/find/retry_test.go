@@ -0,0 +1,257 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vitorsalgado/mocha/v3"
+	"github.com/vitorsalgado/mocha/v3/expect"
+	"github.com/vitorsalgado/mocha/v3/reply"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func TestFindFileStatusRetry(t *testing.T) {
+	t.Parallel()
+
+	backoff := find.NewExponentialBackOffOptions(
+		find.WithInitialInterval(10*time.Millisecond),
+		find.WithMaxInterval(10*time.Millisecond),
+		find.WithMaxElapsedTime(5*time.Second),
+	)
+
+	for _, tc := range []struct {
+		name     string
+		retry    map[string]*find.ExponentialBackOffOptions
+		failure  reply.Reply
+		expected []string
+	}{
+		{"not retried", nil, reply.Status(http.StatusServiceUnavailable), []string{}},
+		{"status class", map[string]*find.ExponentialBackOffOptions{"5xx": backoff},
+			reply.Status(http.StatusServiceUnavailable), []string{"file"}},
+		{"other status", map[string]*find.ExponentialBackOffOptions{"5xx": backoff},
+			reply.Status(http.StatusTooManyRequests), []string{}},
+		{"retry after seconds", map[string]*find.ExponentialBackOffOptions{"429": backoff},
+			reply.Status(http.StatusTooManyRequests).Header("Retry-After", "1"), []string{"file"}},
+		{"retry after date", map[string]*find.ExponentialBackOffOptions{"429": backoff},
+			reply.Status(http.StatusTooManyRequests).
+				Header("Retry-After", time.Now().Add(time.Second).UTC().Format(http.TimeFormat)), []string{"file"}},
+	} {
+		m := mocha.New(t).CloseOnCleanup(t)
+		m.Start()
+
+		m.AddMocks(
+			mocha.Get(expect.URLPath(listingPath)).
+				Reply(reply.OK().BodyString(`<a href="sub/">sub/</a>`)),
+			mocha.Get(expect.URLPath(listingPath+"sub/")).
+				Reply(reply.Seq().Add(tc.failure, reply.OK().BodyString(`<a href="file">file</a>`))))
+
+		finder := find.NewFind(
+			find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithAsync(true),
+			find.WithStatusRetryBackOff(tc.retry),
+		)
+
+		start := time.Now()
+		found, err := finder.Find()
+
+		if len(tc.expected) == 0 {
+			var partial *find.PartialResultError
+
+			assert.ErrorAs(t, err, &partial, tc.name)
+			assert.Len(t, found.Failures, 1, tc.name)
+			assert.Equal(t, find.ErrorClassStatus, found.Failures[0].Class, tc.name)
+			assert.Equal(t, listingPath+"sub/", found.Failures[0].URL.Path, tc.name)
+		} else {
+			assert.Nil(t, err, tc.name)
+		}
+
+		assert.NotNil(t, found, tc.name)
+		assert.ElementsMatch(t, tc.expected, found.BaseNames, tc.name)
+
+		if tc.retry != nil && len(tc.expected) > 0 {
+			m.AssertHits(t, 3)
+		}
+
+		if tc.name == "retry after seconds" {
+			assert.GreaterOrEqual(t, time.Since(start), time.Second, tc.name)
+		}
+	}
+}
+
+func TestFindFileStatusRetryInvalid(t *testing.T) {
+	t.Parallel()
+
+	finder := find.NewFind(
+		find.WithSeedURLs([]string{"http://localhost/"}),
+		find.WithFilenameRegexp(`.+`),
+		find.WithStatusRetryBackOff(map[string]*find.ExponentialBackOffOptions{"6xx": find.DefaultExponentialBackOffOptions}),
+	)
+
+	_, err := finder.Find()
+
+	assert.NotNil(t, err)
+}
+
+// countingPolicy is a RetryPolicy counting the failures it decides on.
+type countingPolicy struct {
+	find.RetryPolicy
+	failures int64
+}
+
+func (p *countingPolicy) Retry(f *find.Failure) (time.Duration, bool) {
+	atomic.AddInt64(&p.failures, 1)
+
+	return p.RetryPolicy.Retry(f)
+}
+
+func TestFindFileRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	backoff := find.NewExponentialBackOffOptions(
+		find.WithInitialInterval(time.Millisecond),
+		find.WithMaxInterval(time.Millisecond),
+		find.WithMaxElapsedTime(5*time.Second),
+	)
+
+	for _, tc := range []struct {
+		name        string
+		maxAttempts int
+		budget      int
+		hits        int
+	}{
+		{"max attempts", 3, 0, 1 + 2*3},
+		{"budget", 3, 1, 1 + 2 + 1},
+	} {
+		m := mocha.New(t).CloseOnCleanup(t)
+		m.Start()
+
+		m.AddMocks(
+			mocha.Get(expect.URLPath(listingPath)).
+				Reply(reply.OK().BodyString(`<a href="a/">a/</a><a href="b/">b/</a>`)),
+			mocha.Get(expect.URLPath(listingPath+"a/")).
+				Reply(reply.Status(http.StatusBadGateway)),
+			mocha.Get(expect.URLPath(listingPath+"b/")).
+				Reply(reply.Status(http.StatusBadGateway)))
+
+		policy := &countingPolicy{
+			RetryPolicy: find.NewExponentialBackOffPolicy(find.IsStatus("502"), backoff, tc.maxAttempts),
+		}
+
+		finder := find.NewFind(
+			find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithAsync(true),
+			find.WithRetryPolicy(policy),
+			find.WithRetryBudget(tc.budget),
+		)
+
+		found, err := finder.Find()
+
+		var partial *find.PartialResultError
+
+		assert.ErrorAs(t, err, &partial, tc.name)
+		assert.NotNil(t, found, tc.name)
+		assert.Empty(t, found.BaseNames, tc.name)
+		assert.Len(t, found.Failures, 2, tc.name)
+
+		attempts := 0
+		for _, v := range found.Failures {
+			assert.Equal(t, http.StatusBadGateway, v.StatusCode, tc.name)
+			attempts += v.Attempts
+		}
+
+		assert.Equal(t, tc.hits-1, attempts, tc.name)
+		m.AssertHits(t, tc.hits)
+		assert.Equal(t, int64(tc.hits-1), atomic.LoadInt64(&policy.failures), tc.name)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	t.Parallel()
+
+	// Listen and close to get a port with no listener.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	_, refusedErr := http.Get("http://" + addr)
+
+	_, dnsErr := http.Get("http://wfind.invalid")
+
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	// The certificate of the server is not trusted.
+	_, authorityErr := http.Get(server.URL)
+
+	for _, tc := range []struct {
+		status   int
+		err      error
+		expected find.ErrorClass
+	}{
+		{0, context.DeadlineExceeded, find.ErrorClassContextDeadline},
+		{0, dnsErr, find.ErrorClassDNS},
+		{0, refusedErr, find.ErrorClassConnRefused},
+		{0, authorityErr, find.ErrorClassTLS},
+		{0, fmt.Errorf("handshake: %w", tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}),
+			find.ErrorClassTLS},
+		{0, fmt.Errorf("tls: not a TLS error"), find.ErrorClassOther},
+		{http.StatusServiceUnavailable, fmt.Errorf("%s", http.StatusText(http.StatusServiceUnavailable)), find.ErrorClassStatus},
+		{0, fmt.Errorf("unknown"), find.ErrorClassOther},
+	} {
+		assert.Equal(t, tc.expected, find.ClassifyError(tc.status, tc.err), tc.err)
+	}
+}
+
+func TestExponentialBackOffPolicyRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	policy := find.NewExponentialBackOffPolicy(
+		func(*find.Failure) bool { return true },
+		find.NewExponentialBackOffOptions(find.WithInitialInterval(10*time.Millisecond)),
+		0,
+	)
+
+	for _, v := range []string{"86400", time.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat)} {
+		delay, ok := policy.Retry(&find.Failure{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": []string{v}},
+			Attempts:   1,
+		})
+
+		assert.True(t, ok, v)
+		assert.Greater(t, delay, time.Minute, v)
+		assert.LessOrEqual(t, delay, 5*time.Minute, v)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/temoto/robotstxt"
+)
+
+const (
+	robotsPath = "/robots.txt"
+
+	// robotsMaxRedirects is the maximum number of redirects followed fetching a robots.txt, as of RFC 9309.
+	robotsMaxRedirects = 5
+)
+
+// robotsEntry is the robots.txt of a host, fetched until it's available or unavailable.
+type robotsEntry struct {
+	mu   sync.Mutex
+	data *robotstxt.RobotsData
+}
+
+// robotsTransport is an HTTP transport skipping the requests disallowed by the robots.txt
+// of their host, and applying its Crawl-delay to the limits of the host.
+// The robots.txt of each host is fetched on its first request, following up to 5 redirects.
+// As of RFC 9309, an unavailable robots.txt (4xx) allows all, while an unreachable one
+// (5xx or network failure) disallows all and is fetched again on the next request.
+type robotsTransport struct {
+	limiter *limiter
+	verbose bool
+	next    http.RoundTripper
+
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+func newRobotsTransport(limiter *limiter, verbose bool, next http.RoundTripper) *robotsTransport {
+	return &robotsTransport{limiter: limiter, verbose: verbose, next: next, hosts: map[string]*robotsEntry{}}
+}
+
+func (t *robotsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == robotsPath {
+		return t.next.RoundTrip(req)
+	}
+
+	agent := req.Header.Get("User-Agent")
+
+	robots := t.robots(req)
+
+	if group := robots.FindGroup(agent); group.CrawlDelay > 0 {
+		t.limiter.host(req.URL).setCrawlDelay(group.CrawlDelay)
+	}
+
+	if !robots.TestAgent(req.URL.EscapedPath(), agent) {
+		return nil, fmt.Errorf("%w: %s", ErrRobotsDisallowed, req.URL)
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// robots returns the robots.txt of the host of the request, fetching it unless already fetched.
+func (t *robotsTransport) robots(req *http.Request) *robotstxt.RobotsData {
+	key := strings.ToLower(req.URL.Scheme + "://" + req.URL.Host)
+
+	t.mu.Lock()
+
+	entry, ok := t.hosts[key]
+	if !ok {
+		entry = &robotsEntry{}
+		t.hosts[key] = entry
+	}
+
+	t.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.data != nil {
+		return entry.data
+	}
+
+	status, body, err := t.fetch(req)
+	if err == nil && status >= 300 && status < 400 {
+		// Too many redirects: the robots.txt is unavailable.
+		status = http.StatusNotFound
+	}
+
+	data, err := robotsData(status, body, err)
+	if err != nil {
+		if t.verbose {
+			log.Printf("error fetching robots.txt of %s, disallowing all: %v\n", req.URL.Host, err)
+		}
+
+		data, _ = robotstxt.FromStatusAndBytes(http.StatusServiceUnavailable, nil)
+
+		return data
+	}
+
+	entry.data = data
+
+	return data
+}
+
+// robotsData returns the robots.txt of the response with status and body to its fetch,
+// or an error if it's unreachable.
+func robotsData(status int, body []byte, err error) (*robotstxt.RobotsData, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	if status >= 500 {
+		//nolint:goerr113
+		return nil, fmt.Errorf("unexpected status %d", status)
+	}
+
+	return robotstxt.FromStatusAndBytes(status, body)
+}
+
+// fetch returns the status and the body of the response to the robots.txt of the host of the
+// request, following up to robotsMaxRedirects redirects.
+func (t *robotsTransport) fetch(req *http.Request) (int, []byte, error) {
+	u := &url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: robotsPath}
+
+	for redirects := 0; ; redirects++ {
+		robotsReq, err := http.NewRequestWithContext(req.Context(), http.MethodGet, u.String(), nil)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		robotsReq.Header.Set("User-Agent", req.Header.Get("User-Agent"))
+
+		res, err := t.next.RoundTrip(robotsReq)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		location := res.Header.Get("Location")
+
+		if !isRedirect(res.StatusCode) || location == "" || redirects == robotsMaxRedirects {
+			body, err := io.ReadAll(res.Body)
+			res.Body.Close()
+
+			return res.StatusCode, body, err
+		}
+
+		res.Body.Close()
+
+		if u, err = u.Parse(location); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+// isRedirect returns whether the HTTP status code is of a redirect.
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
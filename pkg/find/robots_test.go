@@ -0,0 +1,178 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func TestFindFileRespectRobots(t *testing.T) {
+	t.Parallel()
+
+	for respectRobots, expected := range map[bool][]string{
+		false: {"public", "private"},
+		true:  {"public"},
+	} {
+		var privateHits int64
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /pub/linux/private/\nCrawl-delay: 0.1\n")
+		})
+		mux.HandleFunc(listingPath, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<a href="private/">private/</a><a href="public/">public/</a>`)
+		})
+		mux.HandleFunc(listingPath+"public/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<a href="public">public</a>`)
+		})
+		mux.HandleFunc(listingPath+"private/", func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&privateHits, 1)
+			fmt.Fprint(w, `<a href="private">private</a>`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		finder := find.NewFind(
+			find.WithSeedURLs([]string{server.URL + listingPath}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithAsync(true),
+			find.WithRespectRobots(respectRobots),
+		)
+
+		start := time.Now()
+		found, err := finder.Find()
+
+		assert.Nil(t, err)
+		assert.NotNil(t, found)
+		assert.Empty(t, found.Failures)
+		assert.ElementsMatch(t, expected, found.BaseNames)
+
+		if respectRobots {
+			assert.Zero(t, atomic.LoadInt64(&privateHits))
+
+			// The seed and the public folder are requested after the crawl delay.
+			assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+		}
+	}
+}
+
+func TestFindFileRobotsRedirect(t *testing.T) {
+	t.Parallel()
+
+	for redirects, expected := range map[int][]string{
+		5: {"public"},
+		// Beyond 5 redirects the robots.txt is unavailable, allowing all.
+		6: {"public", "private"},
+	} {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/redirect/1", http.StatusMovedPermanently)
+		})
+		mux.HandleFunc("/redirect/", func(w http.ResponseWriter, r *http.Request) {
+			var hop int
+			fmt.Sscanf(r.URL.Path, "/redirect/%d", &hop)
+
+			if hop < redirects {
+				http.Redirect(w, r, fmt.Sprintf("/redirect/%d", hop+1), http.StatusFound)
+
+				return
+			}
+
+			fmt.Fprint(w, "User-agent: *\nDisallow: /pub/linux/private/\n")
+		})
+		mux.HandleFunc(listingPath, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<a href="private/">private/</a><a href="public/">public/</a>`)
+		})
+		mux.HandleFunc(listingPath+"public/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<a href="public">public</a>`)
+		})
+		mux.HandleFunc(listingPath+"private/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<a href="private">private</a>`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		found, err := find.NewFind(
+			find.WithSeedURLs([]string{server.URL + listingPath}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithAsync(true),
+			find.WithRespectRobots(true),
+		).Find()
+
+		assert.Nil(t, err, redirects)
+		assert.NotNil(t, found, redirects)
+		assert.ElementsMatch(t, expected, found.BaseNames, redirects)
+	}
+}
+
+func TestFindFileRobotsUnreachable(t *testing.T) {
+	t.Parallel()
+
+	var robotsHits int64
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		// The robots.txt is unreachable only the first time.
+		if atomic.AddInt64(&robotsHits, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		fmt.Fprint(w, "User-agent: *\nAllow: /\n")
+	})
+	mux.HandleFunc(listingPath+"a/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="a">a</a>`)
+	})
+	mux.HandleFunc(listingPath+"b/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="b">b</a>`)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	found, err := find.NewFind(
+		find.WithSeedURLs([]string{server.URL + listingPath + "a/", server.URL + listingPath + "b/"}),
+		find.WithFilenameRegexp(`.+`),
+		find.WithFileType(find.FileTypeReg),
+		find.WithAsync(true),
+		find.WithRespectRobots(true),
+	).Find()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, found)
+
+	// The seed requested with the robots.txt unreachable is disallowed, and the robots.txt
+	// is fetched again for the other one.
+	assert.Len(t, found.BaseNames, 1)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&robotsHits))
+}
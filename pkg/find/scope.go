@@ -0,0 +1,157 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/maxgio92/wfind/internal/network"
+)
+
+// scope represents the set of hosts and URL prefixes the Find job is confined to.
+// It's fixed once created, so that neither links nor redirects can widen it.
+type scope struct {
+	// hosts are the hosts of the seed URLs.
+	hosts []string
+
+	// domains are the patterns of the additional allowed hosts.
+	domains []string
+
+	// roots are the seed URLs, below which the Find job is confined.
+	roots []*url.URL
+}
+
+func newScope(roots []*url.URL, domains []string) *scope {
+	return &scope{
+		hosts:   getHostnamesFromURLs(roots),
+		domains: domains,
+		roots:   roots,
+	}
+}
+
+// allows returns whether the host of the URL u is one of the seed URLs hosts,
+// or matches one of the allowed domain patterns.
+func (s *scope) allows(u *url.URL) bool {
+	if s.seedHost(u) {
+		return true
+	}
+
+	for _, v := range s.domains {
+		if network.HostMatches(v, u) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// seedHost returns whether the host of the URL u is one of the seed URLs hosts.
+func (s *scope) seedHost(u *url.URL) bool {
+	for _, v := range s.hosts {
+		if strings.EqualFold(u.Host, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contains returns whether the absolute URL u is on the host of, and below the path of,
+// one of the scope roots.
+func (s *scope) contains(u *url.URL) bool {
+	for _, v := range s.roots {
+		if below(v, u) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// escapes returns whether the absolute URL u is on one of the seed URLs hosts,
+// but outside the seeds hierarchy.
+func (s *scope) escapes(u *url.URL) bool {
+	return s.seedHost(u) && !s.contains(u)
+}
+
+// below returns whether the absolute URL u is on the host of, and below the folder of,
+// the absolute URL base.
+func below(base, u *url.URL) bool {
+	dir := base.Path
+	if !strings.HasSuffix(dir, RootDir) {
+		dir = path.Dir(dir) + RootDir
+	}
+
+	return strings.EqualFold(u.Host, base.Host) && strings.HasPrefix(u.Path, dir)
+}
+
+// follow returns the absolute URL of href, found in the listing at base, and whether
+// the Find job should examine it.
+func (o *Options) follow(s *scope, base *url.URL, href string) (*url.URL, bool) {
+	u, kind := classifyHref(base, href)
+
+	switch kind {
+	case hrefKindFolder, hrefKindFile:
+		// Do not escape the seeds hierarchy, nor the one of the listings outside of it,
+		// reached by external links or redirects to other allowed hosts.
+		return u, o.AllowEscape || s.contains(u) || below(base, u)
+	case hrefKindExternal:
+		// The external links to the seed hosts must stay within the seeds hierarchy.
+		return u, o.FollowExternalLinks && s.allows(u) && (o.AllowEscape || !s.escapes(u))
+	default:
+		// Skip sort links, fragments, non-HTTP references, and parents.
+		return u, false
+	}
+}
+
+// checkRedirect returns an error if the Find job should not follow the redirect of
+// the request via[0] to req, unless escaping the seeds hierarchy is allowed.
+// It implements the http.Client.CheckRedirect contract.
+func (s *scope) checkRedirect(req *http.Request, via []*http.Request, allowEscape bool) error {
+	if !s.allows(req.URL) {
+		//nolint:goerr113
+		return fmt.Errorf("not following redirect to %s because its host is not allowed", req.URL.Host)
+	}
+
+	// The redirects to the seed hosts must stay within the seeds hierarchy.
+	if !allowEscape && s.escapes(req.URL) {
+		//nolint:goerr113
+		return fmt.Errorf("not following redirect to %s because it's outside the seed URLs", req.URL)
+	}
+
+	if len(via) >= maxRedirects {
+		return http.ErrUseLastResponse
+	}
+
+	return nil
+}
+
+// redirectHandler returns the function to check the redirects of the requests, and to
+// authenticate them to the host they are redirected to.
+func (o *Options) redirectHandler(s *scope) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if err := s.checkRedirect(req, via, o.AllowEscape); err != nil {
+			return err
+		}
+
+		return o.authorize(s, req.URL, req.Header)
+	}
+}
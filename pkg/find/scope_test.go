@@ -0,0 +1,238 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vitorsalgado/mocha/v3"
+	"github.com/vitorsalgado/mocha/v3/expect"
+	"github.com/vitorsalgado/mocha/v3/reply"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func TestFindFileConfined(t *testing.T) {
+	t.Parallel()
+
+	for allowEscape, expected := range map[bool][]string{
+		false: {"inside"},
+		true:  {"inside", "outside"},
+	} {
+		m := mocha.New(t).CloseOnCleanup(t)
+		m.Start()
+
+		m.AddMocks(
+			mocha.Get(expect.URLPath(listingPath)).
+				Reply(reply.OK().BodyString(`<a href="/other/tree/">tree/</a><a href="inside">inside</a>`)),
+			mocha.Get(expect.URLPath("/other/tree/")).
+				Reply(reply.OK().BodyString(`<a href="outside">outside</a>`)))
+
+		finder := find.NewFind(
+			find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithAllowEscape(allowEscape),
+		)
+
+		found, err := finder.Find()
+
+		assert.Nil(t, err)
+		assert.NotNil(t, found)
+		assert.ElementsMatch(t, expected, found.BaseNames)
+	}
+}
+
+// localhostURL returns the URL of the mock server m, with localhost as host,
+// so that it's seen by the Find job as a host different from the one of m.URL().
+func localhostURL(m *mocha.Mocha) string {
+	return strings.Replace(m.URL(), "127.0.0.1", "localhost", 1)
+}
+
+func TestFindFileExternalLinks(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		followExternalLinks bool
+		allowedDomains      []string
+		expected            []string
+	}{
+		{false, nil, []string{"local"}},
+		{false, []string{"localhost"}, []string{"local"}},
+		{true, nil, []string{"local"}},
+		{true, []string{"local*"}, []string{"local", "remote"}},
+	} {
+		mirror := mocha.New(t).CloseOnCleanup(t)
+		mirror.Start()
+
+		mirror.AddMocks(
+			mocha.Get(expect.URLPath("/mirror/")).
+				Reply(reply.OK().BodyString(`<a href="../">../</a><a href="remote">remote</a>`)))
+
+		m := mocha.New(t).CloseOnCleanup(t)
+		m.Start()
+
+		m.AddMocks(
+			mocha.Get(expect.URLPath(listingPath)).
+				Reply(reply.OK().BodyString(fmt.Sprintf(`<a href="local">local</a><a href="%s/mirror/">mirror/</a>`,
+					localhostURL(mirror)))))
+
+		finder := find.NewFind(
+			find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithFollowExternalLinks(tc.followExternalLinks),
+			find.WithAllowedDomains(tc.allowedDomains),
+		)
+
+		found, err := finder.Find()
+
+		assert.Nil(t, err)
+		assert.NotNil(t, found)
+		assert.ElementsMatch(t, tc.expected, found.BaseNames)
+	}
+}
+
+func TestFindFileExternalLinksConfined(t *testing.T) {
+	t.Parallel()
+
+	for allowEscape, expected := range map[bool][]string{
+		false: {"inside"},
+		true:  {"inside", "escaped"},
+	} {
+		m := mocha.New(t).CloseOnCleanup(t)
+		m.Start()
+
+		mirror := mocha.New(t).CloseOnCleanup(t)
+		mirror.Start()
+
+		// The listing on the second host links back to the seed host, inside and outside the seed.
+		mirror.AddMocks(
+			mocha.Get(expect.URLPath("/mirror/")).
+				Reply(reply.OK().BodyString(fmt.Sprintf(`<a href="%s%ssub/">sub/</a><a href="%s/other/">other/</a>`,
+					m.URL(), listingPath, m.URL()))))
+
+		m.AddMocks(
+			mocha.Get(expect.URLPath(listingPath)).
+				Reply(reply.OK().BodyString(fmt.Sprintf(`<a href="%s/mirror/">mirror/</a>`, localhostURL(mirror)))),
+			mocha.Get(expect.URLPath(listingPath+"sub/")).
+				Reply(reply.OK().BodyString(`<a href="inside">inside</a>`)),
+			mocha.Get(expect.URLPath("/other/")).
+				Reply(reply.OK().BodyString(`<a href="escaped">escaped</a>`)))
+
+		found, err := find.NewFind(
+			find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithFollowExternalLinks(true),
+			find.WithAllowedDomains([]string{"localhost"}),
+			find.WithAllowEscape(allowEscape),
+		).Find()
+
+		assert.Nil(t, err, allowEscape)
+		assert.NotNil(t, found, allowEscape)
+		assert.ElementsMatch(t, expected, found.BaseNames, allowEscape)
+	}
+}
+
+func TestFindFileRedirect(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		allowedDomains []string
+		expected       []string
+	}{
+		{nil, nil},
+		{[]string{"localhost"}, []string{"remote"}},
+	} {
+		mirror := mocha.New(t).CloseOnCleanup(t)
+		mirror.Start()
+
+		mirror.AddMocks(
+			mocha.Get(expect.URLPath("/mirror/")).
+				Reply(reply.OK().BodyString(`<a href="sub/">sub/</a><a href="remote">remote</a>`)),
+			mocha.Get(expect.URLPath("/mirror/sub/")).
+				Reply(reply.OK().BodyString(`<a href="/">/</a>`)))
+
+		m := mocha.New(t).CloseOnCleanup(t)
+		m.Start()
+
+		m.AddMocks(
+			mocha.Get(expect.URLPath(listingPath)).
+				Reply(reply.Status(http.StatusFound).Header("Location", localhostURL(mirror)+"/mirror/")))
+
+		finder := find.NewFind(
+			find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithAllowedDomains(tc.allowedDomains),
+		)
+
+		found, err := finder.Find()
+
+		if tc.expected == nil {
+			assert.NotNil(t, err)
+
+			continue
+		}
+
+		assert.Nil(t, err)
+		assert.NotNil(t, found)
+		assert.ElementsMatch(t, tc.expected, found.BaseNames)
+	}
+}
+
+func TestFindFileRedirectConfined(t *testing.T) {
+	t.Parallel()
+
+	m := mocha.New(t).CloseOnCleanup(t)
+	m.Start()
+
+	m.AddMocks(
+		mocha.Get(expect.URLPath(listingPath)).
+			Reply(reply.OK().BodyString(`<a href="a/">a/</a><a href="inside">inside</a>`)),
+		mocha.Get(expect.URLPath(listingPath+"a/")).
+			Reply(reply.Status(http.StatusFound).Header("Location", "/")),
+		mocha.Get(expect.URLPath("/")).
+			Reply(reply.OK().BodyString(`<a href="/toplevel">toplevel</a><a href="/other/">other/</a>`)),
+		mocha.Get(expect.URLPath("/other/")).
+			Reply(reply.OK().BodyString(`<a href="escaped">escaped</a>`)))
+
+	finder := find.NewFind(
+		find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+		find.WithFilenameRegexp(`.+`),
+		find.WithFileType(find.FileTypeReg),
+		find.WithRecursive(true),
+	)
+
+	found, err := finder.Find()
+
+	// The redirect outside the seed is not followed, and reported as failed.
+	var partial *find.PartialResultError
+
+	assert.ErrorAs(t, err, &partial)
+	assert.NotNil(t, found)
+	assert.ElementsMatch(t, []string{"inside"}, found.BaseNames)
+}
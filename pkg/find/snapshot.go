@@ -0,0 +1,317 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// SnapshotHeader is the header of the listings served from a Snapshot, as not modified.
+const SnapshotHeader = "X-Wfind-Snapshot"
+
+// Snapshot is the state of the listings examined by a Find job, with their ETag and Last-Modified
+// headers and their links, so that the next Find jobs skip the subtrees whose listings are not modified,
+// reusing their previous links.
+//
+// A listing is not modified if its server replies 304 Not Modified to the conditional request,
+// or with the same ETag or Last-Modified. The subtree of a listing not modified is assumed to be
+// not modified as well, and its listings are served from the Snapshot without requests.
+type Snapshot struct {
+	mu       sync.Mutex
+	previous map[string]*SnapshotListing
+	current  map[string]*SnapshotListing
+}
+
+// SnapshotListing is the state of a listing in a Snapshot.
+type SnapshotListing struct {
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"lastModified,omitempty"`
+	ContentType  string   `json:"contentType,omitempty"`
+	Links        []string `json:"links"`
+}
+
+// NewSnapshot returns an empty Snapshot.
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		previous: map[string]*SnapshotListing{},
+		current:  map[string]*SnapshotListing{},
+	}
+}
+
+// LoadSnapshot returns the Snapshot saved to the file at path.
+// If the file does not exist, the Snapshot is empty.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	s := NewSnapshot()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &s.previous); err != nil {
+		return nil, fmt.Errorf("error parsing the snapshot %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Save writes the listings examined by the Find job to the file at path.
+func (s *Snapshot) Save(path string) error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.current, "", "  ")
+	s.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
+
+// Len returns the number of the listings examined by the Find job.
+func (s *Snapshot) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.current)
+}
+
+func (s *Snapshot) get(key string) *SnapshotListing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.previous[key]
+}
+
+func (s *Snapshot) put(key string, listing *SnapshotListing) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.current[key] = listing
+}
+
+// snapshotTransport makes conditional requests for the listings in the snapshot, serving
+// the ones not modified and their subtrees from it, and records the listings retrieved.
+type snapshotTransport struct {
+	snapshot *Snapshot
+	next     http.RoundTripper
+
+	mu        sync.Mutex
+	unchanged map[string]bool
+}
+
+func newSnapshotTransport(snapshot *Snapshot, next http.RoundTripper) *snapshotTransport {
+	return &snapshotTransport{snapshot: snapshot, next: next, unchanged: map[string]bool{}}
+}
+
+func (t *snapshotTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.URL.String()
+
+	previous := t.snapshot.get(key)
+	if previous == nil {
+		return t.record(key, req)
+	}
+
+	// The subtrees of the listings not modified are not requested.
+	if t.inUnchanged(req.URL) {
+		return t.reuse(key, req, previous), nil
+	}
+
+	req = req.Clone(req.Context())
+
+	if previous.ETag != "" {
+		req.Header.Set("If-None-Match", previous.ETag)
+	}
+
+	if previous.LastModified != "" {
+		req.Header.Set("If-Modified-Since", previous.LastModified)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	notModified := resp.StatusCode == http.StatusNotModified ||
+		(resp.StatusCode == http.StatusOK &&
+			((previous.ETag != "" && resp.Header.Get("ETag") == previous.ETag) ||
+				(previous.LastModified != "" && resp.Header.Get("Last-Modified") == previous.LastModified)))
+
+	if !notModified {
+		return t.recordResponse(key, resp), nil
+	}
+
+	resp.Body.Close()
+
+	return t.reuse(key, req, previous), nil
+}
+
+// inUnchanged returns whether u is below a listing not modified.
+func (t *snapshotTransport) inUnchanged(u *url.URL) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parent := *u
+	parent.RawQuery = ""
+	parent.Fragment = ""
+
+	for {
+		p := strings.TrimSuffix(parent.Path, "/")
+
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			return false
+		}
+
+		parent.Path = p[:i+1]
+		parent.RawPath = ""
+
+		if t.unchanged[parent.String()] {
+			return true
+		}
+	}
+}
+
+// reuse returns the listing at key not modified, as found in the snapshot.
+func (t *snapshotTransport) reuse(key string, req *http.Request, listing *SnapshotListing) *http.Response {
+	t.mu.Lock()
+	t.unchanged[key] = true
+	t.mu.Unlock()
+
+	t.snapshot.put(key, listing)
+
+	var body bytes.Buffer
+
+	for _, v := range listing.Links {
+		fmt.Fprintf(&body, "<a href=\"%s\"></a>\n", html.EscapeString(v))
+	}
+
+	header := http.Header{}
+	header.Set(SnapshotHeader, "not-modified")
+
+	if listing.ContentType != "" {
+		header.Set("Content-Type", listing.ContentType)
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(&body),
+		ContentLength: int64(body.Len()),
+		Request:       req,
+	}
+}
+
+// record makes the request of the listing at key, recording it.
+func (t *snapshotTransport) record(key string, req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.recordResponse(key, resp), nil
+}
+
+// recordResponse returns resp, recording it as the listing at key once its body is read whole.
+func (t *snapshotTransport) recordResponse(key string, resp *http.Response) *http.Response {
+	if resp.StatusCode != http.StatusOK ||
+		!strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "html") {
+		return resp
+	}
+
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		done: func(body []byte) {
+			t.snapshot.put(key, &SnapshotListing{
+				ETag:         resp.Header.Get("ETag"),
+				LastModified: resp.Header.Get("Last-Modified"),
+				ContentType:  resp.Header.Get("Content-Type"),
+				Links:        listingLinks(body),
+			})
+		},
+	}
+
+	return resp
+}
+
+// recordingBody is a response body passed to done once read until EOF.
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	done func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+
+	if errors.Is(err, io.EOF) && b.done != nil {
+		b.done(b.buf.Bytes())
+		b.done = nil
+	}
+
+	return n, err
+}
+
+// listingLinks returns the references of the links of the HTML document body.
+func listingLinks(body []byte) []string {
+	links := []string{}
+	tokenizer := xhtml.NewTokenizer(bytes.NewReader(body))
+
+	for {
+		switch tokenizer.Next() {
+		case xhtml.ErrorToken:
+			return links
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			if string(name) != "a" {
+				continue
+			}
+
+			for hasAttr {
+				var key, value []byte
+
+				key, value, hasAttr = tokenizer.TagAttr()
+				if string(key) == HTMLAttrRef {
+					links = append(links, string(value))
+
+					break
+				}
+			}
+		}
+	}
+}
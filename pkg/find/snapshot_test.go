@@ -0,0 +1,126 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func TestFindFileSnapshot(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+
+	listings := map[string]string{
+		listingPath:               `<a href="sub/">sub/</a><a href="root">root</a>`,
+		listingPath + "sub/":      `<a href="deep/">deep/</a><a href="sub">sub</a>`,
+		listingPath + "sub/deep/": `<a href="deep">deep</a>`,
+		listingPath + "other/":    `<a href="other">other</a>`,
+	}
+	etags := map[string]string{}
+
+	for k := range listings {
+		etags[k] = `"v1"`
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests = append(requests, r.URL.Path)
+
+		if r.Header.Get("If-None-Match") == etags[r.URL.Path] {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("ETag", etags[r.URL.Path])
+		fmt.Fprint(w, listings[r.URL.Path])
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	for _, tc := range []struct {
+		name     string
+		change   func()
+		requests []string
+		expected []string
+	}{
+		{
+			"first", func() {},
+			[]string{listingPath, listingPath + "sub/", listingPath + "sub/deep/"},
+			[]string{"root", "sub", "deep"},
+		},
+		{
+			"not modified", func() {},
+			[]string{listingPath},
+			[]string{"root", "sub", "deep"},
+		},
+		{
+			"modified", func() {
+				listings[listingPath] += `<a href="other/">other/</a>`
+				etags[listingPath] = `"v2"`
+			},
+			[]string{listingPath, listingPath + "sub/", listingPath + "other/"},
+			[]string{"root", "sub", "deep", "other"},
+		},
+	} {
+		snapshot, err := find.LoadSnapshot(path)
+		assert.Nil(t, err, tc.name)
+
+		mu.Lock()
+		tc.change()
+		requests = nil
+		mu.Unlock()
+
+		found, err := find.NewFind(
+			find.WithSeedURLs([]string{server.URL + listingPath}),
+			find.WithFilenameRegexp(`.+`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithSnapshot(snapshot),
+		).Find()
+
+		assert.Nil(t, err, tc.name)
+		assert.ElementsMatch(t, tc.expected, found.BaseNames, tc.name)
+
+		mu.Lock()
+		assert.ElementsMatch(t, tc.requests, requests, tc.name)
+		mu.Unlock()
+
+		assert.Nil(t, snapshot.Save(path), tc.name)
+		// Each listing has a file, and all the listings are in the snapshot.
+		assert.Equal(t, len(tc.expected), snapshot.Len(), tc.name)
+	}
+}
@@ -0,0 +1,297 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SortKey is the key the entries of the Result are sorted by.
+type SortKey string
+
+const (
+	// SortPath sorts the entries by URL, comparing their paths segment by segment,
+	// so that the entries of a folder follow it.
+	SortPath SortKey = "path"
+
+	// SortName sorts the entries by name.
+	SortName SortKey = "name"
+
+	// SortNatural sorts the entries by name, case-insensitively and comparing the numbers
+	// in the names by value.
+	SortNatural SortKey = "natural"
+
+	// SortVersion sorts the entries by the version in their names, with the pre-releases
+	// before their releases, like sort -V.
+	SortVersion SortKey = "version"
+
+	// SortSize sorts the entries by the size reported by their listing.
+	SortSize SortKey = "size"
+
+	// SortMtime sorts the entries by the modification time reported by their listing.
+	SortMtime SortKey = "mtime"
+
+	// SortNone keeps the entries in the order they're found, which varies between asynchronous runs.
+	SortNone SortKey = "none"
+)
+
+// SortKeys are the supported sort keys.
+var SortKeys = []SortKey{SortPath, SortName, SortNatural, SortVersion, SortSize, SortMtime, SortNone}
+
+// validate returns ErrUnsupportedSortKey if the sort key k is not supported.
+func (k SortKey) validate() error {
+	for _, v := range SortKeys {
+		if k == v {
+			return nil
+		}
+	}
+
+	return ErrUnsupportedSortKey
+}
+
+// Sort sorts the entries of the Result by key, in reverse order if reverse, and rebuilds
+// its BaseNames and URLs from them. The entries equal by key are sorted by path.
+func (r *Result) Sort(key SortKey, reverse bool) {
+	if key == SortNone {
+		return
+	}
+
+	entries := make([]sortedEntry, len(r.Entries))
+	for i, v := range r.Entries {
+		entries[i] = newSortedEntry(v)
+	}
+
+	compare := sortCompare(key)
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if reverse {
+			i, j = j, i
+		}
+
+		if c := compare(entries[i], entries[j]); c != 0 {
+			return c < 0
+		}
+
+		return comparePaths(entries[i].path, entries[j].path) < 0
+	})
+
+	r.BaseNames = make([]string, len(entries))
+	r.URLs = make([]string, len(entries))
+
+	for i, v := range entries {
+		r.Entries[i] = v.Entry
+		r.BaseNames[i] = v.Name
+		r.URLs[i] = v.URL
+	}
+}
+
+// sortedEntry is an Entry along with its sort keys.
+type sortedEntry struct {
+	Entry
+
+	// path is the host followed by the path segments of the entry URL.
+	path []string
+}
+
+func newSortedEntry(entry Entry) sortedEntry {
+	u, err := url.Parse(entry.URL)
+	if err != nil {
+		return sortedEntry{Entry: entry, path: []string{entry.URL}}
+	}
+
+	return sortedEntry{Entry: entry, path: append([]string{u.Host}, strings.Split(u.Path, "/")...)}
+}
+
+// sortCompare returns the comparison of the entries by key, negative when a sorts before b.
+func sortCompare(key SortKey) func(a, b sortedEntry) int {
+	switch key {
+	case SortName:
+		return func(a, b sortedEntry) int {
+			return strings.Compare(a.Name, b.Name)
+		}
+	case SortNatural:
+		return func(a, b sortedEntry) int {
+			return compareNatural(a.Name, b.Name)
+		}
+	case SortVersion:
+		return func(a, b sortedEntry) int {
+			return compareVersions(a.Name, b.Name)
+		}
+	case SortSize:
+		return func(a, b sortedEntry) int {
+			return compareInts(a.Size, b.Size)
+		}
+	case SortMtime:
+		return func(a, b sortedEntry) int {
+			return compareInts(a.ModTime.UnixNano(), b.ModTime.UnixNano())
+		}
+	default:
+		return func(a, b sortedEntry) int {
+			return 0
+		}
+	}
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePaths compares the path segments a and b.
+func comparePaths(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareInts(int64(len(a)), int64(len(b)))
+}
+
+// splitDigits splits s into the runs of digits and of non-digits.
+func splitDigits(s string) []string {
+	tokens := []string{}
+
+	for i := 0; i < len(s); {
+		j := i + 1
+		for j < len(s) && isDigit(s[j]) == isDigit(s[i]) {
+			j++
+		}
+
+		tokens = append(tokens, s[i:j])
+		i = j
+	}
+
+	return tokens
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// compareNumbers compares the runs of digits a and b by value.
+func compareNumbers(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return compareInts(int64(len(a)), int64(len(b)))
+	}
+
+	return strings.Compare(a, b)
+}
+
+// compareTokens compares the runs of digits or non-digits a and b.
+func compareTokens(a, b string) int {
+	if isDigit(a[0]) && isDigit(b[0]) {
+		return compareNumbers(a, b)
+	}
+
+	return strings.Compare(a, b)
+}
+
+// compareNatural compares the strings a and b case-insensitively, and their numbers by value.
+func compareNatural(a, b string) int {
+	ta, tb := splitDigits(strings.ToLower(a)), splitDigits(strings.ToLower(b))
+
+	for i := 0; i < len(ta) && i < len(tb); i++ {
+		if c := compareTokens(ta[i], tb[i]); c != 0 {
+			return c
+		}
+	}
+
+	if c := compareInts(int64(len(ta)), int64(len(tb))); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a, b)
+}
+
+// fileSuffixRegex matches the file name extensions, which are not part of the version.
+var fileSuffixRegex = regexp.MustCompile(`(\.[A-Za-z~][A-Za-z0-9~]*)*$`)
+
+// compareVersions compares the versions in the file names a and b.
+func compareVersions(a, b string) int {
+	sa, sb := fileSuffixRegex.FindStringIndex(a)[0], fileSuffixRegex.FindStringIndex(b)[0]
+
+	if c := compareVersionTokens(splitDigits(a[:sa]), splitDigits(b[:sb])); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a[sa:], b[sb:])
+}
+
+func compareVersionTokens(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		pa, pb := isPreRelease(a[i]), isPreRelease(b[i])
+		if pa != pb {
+			if pa {
+				return -1
+			}
+
+			return 1
+		}
+
+		if c := compareTokens(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	// The versions with more components are greater, unless they are pre-releases.
+	switch {
+	case len(a) > len(b):
+		if isPreRelease(a[len(b)]) {
+			return -1
+		}
+
+		return 1
+	case len(a) < len(b):
+		if isPreRelease(b[len(a)]) {
+			return 1
+		}
+
+		return -1
+	default:
+		return 0
+	}
+}
+
+// preReleases are the prefixes of the pre-release components of the versions.
+var preReleases = []string{"alpha", "beta", "pre", "rc", "~"}
+
+// isPreRelease returns whether the version component of non-digits s introduces a pre-release.
+func isPreRelease(s string) bool {
+	s = strings.ToLower(strings.TrimLeftFunc(s, func(r rune) bool {
+		return r != '~' && (unicode.IsPunct(r) || unicode.IsSpace(r))
+	}))
+
+	for _, v := range preReleases {
+		if strings.HasPrefix(s, v) {
+			return true
+		}
+	}
+
+	return false
+}
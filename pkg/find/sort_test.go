@@ -0,0 +1,210 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+func newSortResult(names ...string) *find.Result {
+	r := &find.Result{}
+
+	for i, v := range names {
+		entry := find.Entry{
+			Name:    v,
+			URL:     "http://example.org/pub/" + v,
+			Size:    int64(len(v)),
+			ModTime: time.Unix(int64(len(names)-i), 0),
+		}
+
+		r.BaseNames = append(r.BaseNames, entry.Name)
+		r.URLs = append(r.URLs, entry.URL)
+		r.Entries = append(r.Entries, entry)
+	}
+
+	return r
+}
+
+func TestResultSort(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		key      find.SortKey
+		reverse  bool
+		names    []string
+		expected []string
+	}{
+		{find.SortNone, false, []string{"b", "a"}, []string{"b", "a"}},
+		{find.SortName, false, []string{"b", "a", "B"}, []string{"B", "a", "b"}},
+		{find.SortName, true, []string{"b", "a", "B"}, []string{"b", "a", "B"}},
+		{find.SortPath, false, []string{"a-b/c", "a/c", "a/"}, []string{"a/", "a/c", "a-b/c"}},
+		{find.SortNatural, false, []string{"f10", "F9", "f1"}, []string{"f1", "F9", "f10"}},
+		{
+			find.SortVersion, false,
+			[]string{"linux-5.10.tar.xz", "linux-5.9.tar.xz", "linux-5.10.1.tar.xz", "linux-5.10-rc1.tar.xz"},
+			[]string{"linux-5.9.tar.xz", "linux-5.10-rc1.tar.xz", "linux-5.10.tar.xz", "linux-5.10.1.tar.xz"},
+		},
+		{find.SortVersion, false, []string{"v1.0", "v1.0~beta", "v1.0-alpha"}, []string{"v1.0-alpha", "v1.0~beta", "v1.0"}},
+		// The entries equal by key are in path order.
+		{find.SortSize, false, []string{"ccc", "bb", "aa"}, []string{"aa", "bb", "ccc"}},
+		{find.SortMtime, false, []string{"a", "b", "c"}, []string{"c", "b", "a"}},
+		{find.SortMtime, true, []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+	} {
+		r := newSortResult(tc.names...)
+		r.Sort(tc.key, tc.reverse)
+
+		assert.Equal(t, tc.expected, r.BaseNames, "%s, reverse %t", tc.key, tc.reverse)
+
+		for i, v := range r.Entries {
+			assert.Equal(t, r.BaseNames[i], v.Name)
+			assert.Equal(t, r.URLs[i], v.URL)
+		}
+	}
+}
+
+func TestResultSortMismatched(t *testing.T) {
+	t.Parallel()
+
+	r := newSortResult("b", "a")
+	r.BaseNames = r.BaseNames[:1]
+	r.URLs = append(r.URLs, "https://example.com/c")
+
+	assert.NotPanics(t, func() { r.Sort(find.SortName, false) })
+	assert.Equal(t, []string{"a", "b"}, r.BaseNames)
+	assert.Len(t, r.URLs, 2)
+}
+
+func TestFindFileSort(t *testing.T) {
+	t.Parallel()
+
+	for _, fixture := range listingFixtures {
+		fixture := fixture
+
+		t.Run(fixture, func(t *testing.T) {
+			t.Parallel()
+
+			for _, tc := range []struct {
+				key      find.SortKey
+				reverse  bool
+				expected []string
+			}{
+				{"", false, []string{"README", "kernel", "sha256sums.asc", "utils"}},
+				{find.SortPath, true, []string{"utils", "sha256sums.asc", "kernel", "README"}},
+				{find.SortNatural, false, []string{"kernel", "README", "sha256sums.asc", "utils"}},
+				{find.SortSize, false, []string{"kernel", "utils", "README", "sha256sums.asc"}},
+				{find.SortMtime, false, []string{"README", "utils", "kernel", "sha256sums.asc"}},
+				{find.SortMtime, true, []string{"sha256sums.asc", "kernel", "utils", "README"}},
+			} {
+				m := initListingWebServer(t, fixture)
+
+				finder := find.NewFind(
+					find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+					find.WithFilenameRegexp(`.+`),
+					find.WithFileType("f,d"),
+					find.WithRecursive(false),
+					find.WithSort(tc.key),
+					find.WithReverse(tc.reverse),
+				)
+
+				found, err := finder.Find()
+
+				assert.Nil(t, err)
+				assert.NotNil(t, found)
+				assert.Equal(t, tc.expected, found.BaseNames, "%s, reverse %t", tc.key, tc.reverse)
+			}
+		})
+	}
+}
+
+func TestFindFileListingMetadata(t *testing.T) {
+	t.Parallel()
+
+	for fixture, size := range map[string]int64{
+		"apache.html": 1228,
+		"nginx.html":  1234,
+	} {
+		m := initListingWebServer(t, fixture)
+
+		finder := find.NewFind(
+			find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+			find.WithFilenameRegexp(`^README$`),
+			find.WithRecursive(false),
+		)
+
+		found, err := finder.Find()
+
+		assert.Nil(t, err)
+		assert.NotNil(t, found)
+		assert.Len(t, found.Entries, 1)
+		assert.Equal(t, size, found.Entries[0].Size, fixture)
+		assert.Equal(t, time.Date(2022, time.November, 23, 17, 2, 0, 0, time.UTC), found.Entries[0].ModTime, fixture)
+	}
+}
+
+func TestFindFileStreamed(t *testing.T) {
+	t.Parallel()
+
+	m := initListingWebServer(t, "nginx.html")
+
+	var (
+		mu       sync.Mutex
+		streamed []string
+	)
+
+	finder := find.NewFind(
+		find.WithSeedURLs([]string{fmt.Sprintf("%s%s", m.URL(), listingPath)}),
+		find.WithFilenameRegexp(`.+`),
+		find.WithFileType("f,d"),
+		find.WithRecursive(false),
+		find.WithSort(find.SortNone),
+		find.WithEntryHandler(func(entry find.Entry) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			streamed = append(streamed, entry.Name)
+		}),
+	)
+
+	found, err := finder.Find()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, found)
+	// The entries are in the order they're found, which is the listing order when synchronous.
+	assert.Equal(t, []string{"kernel", "utils", "README", "sha256sums.asc"}, streamed)
+	assert.Equal(t, streamed, found.BaseNames)
+}
+
+func TestFindFileUnsupportedSortKey(t *testing.T) {
+	t.Parallel()
+
+	finder := find.NewFind(
+		find.WithSeedURLs([]string{"http://localhost" + listingPath}),
+		find.WithFilenameRegexp(`.+`),
+		find.WithSort("random"),
+	)
+
+	_, err := finder.Find()
+
+	assert.ErrorIs(t, err, find.ErrUnsupportedSortKey)
+}
@@ -0,0 +1,372 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bits-and-blooms/bloom/v3"
+	"github.com/gocolly/colly/storage"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The prefixes of the keys in a Storage.
+const (
+	visitedKeyPrefix = "visited:"
+	resultKeyPrefix  = "result:"
+	collyKeyPrefix   = "request:"
+)
+
+const (
+	// DefaultBloomCapacity is the default number of keys a BloomStorage is sized for.
+	DefaultBloomCapacity = 10000000
+
+	// DefaultBloomFPRate is the default false positive rate of a BloomStorage.
+	DefaultBloomFPRate = 0.001
+)
+
+// Storage stores the keys of the URLs visited and of the files found by a Find job.
+// A Storage is used by a single Find job, and must be safe for concurrent use.
+//
+// The files found are kept in memory regardless of the Storage, as they're returned with the Result,
+// and so are the URLs to visit, unless the Storage is a QueueStorage.
+type Storage interface {
+	// Add adds the key, returning whether it was not already present.
+	Add(key string) (bool, error)
+
+	// Close releases the resources of the Storage.
+	Close() error
+}
+
+// StorageLister is a Storage able to list its keys, so that the Find job can be checkpointed
+// with the URLs visited.
+type StorageLister interface {
+	Storage
+
+	// Keys returns the keys with the prefix, sorted.
+	Keys(prefix string) ([]string, error)
+}
+
+// QueueStorage is a Storage keeping the queue of the URLs to visit too, so that the URLs found
+// are visited in batches, and the memory is bounded by the size of a batch rather than by the
+// number of the URLs found and not visited yet.
+type QueueStorage interface {
+	Storage
+
+	// Push appends the URL u to the queue.
+	Push(u string) error
+
+	// Pop removes the first URL from the queue and returns it, or false if the queue is empty.
+	Pop() (string, bool, error)
+
+	// Queued returns the URLs in the queue, in order.
+	Queued() ([]string, error)
+}
+
+// MemoryStorage is a Storage keeping the keys in memory. It's the default Storage.
+type MemoryStorage struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+// NewMemoryStorage returns an empty MemoryStorage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{keys: map[string]struct{}{}}
+}
+
+func (s *MemoryStorage) Add(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[key]; ok {
+		return false, nil
+	}
+
+	s.keys[key] = struct{}{}
+
+	return true, nil
+}
+
+func (s *MemoryStorage) Keys(prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := []string{}
+
+	for k := range s.keys {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+// The buckets of the keys and of the queue of a DiskStorage.
+var (
+	diskStorageBucket      = []byte("keys")
+	diskStorageQueueBucket = []byte("queue")
+)
+
+// DiskStorage is a QueueStorage keeping the keys and the queue in an embedded key-value database
+// on disk, so that the memory is bounded regardless of the number of keys.
+type DiskStorage struct {
+	db *bolt.DB
+}
+
+// NewDiskStorage returns an empty DiskStorage, whose database is a new file in the directory dir,
+// removed once closed. If dir is empty, the default directory for temporary files is used.
+func NewDiskStorage(dir string) (*DiskStorage, error) {
+	f, err := os.CreateTemp(dir, "wfind-storage-*.db")
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating the storage database")
+	}
+
+	f.Close()
+
+	// The database is a scratch space, not to be recovered after a crash.
+	db, err := bolt.Open(f.Name(), 0o600, &bolt.Options{Timeout: time.Second, NoSync: true, NoFreelistSync: true})
+	if err != nil {
+		os.Remove(f.Name())
+
+		return nil, errors.Wrap(err, "error opening the storage database")
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(diskStorageBucket); err != nil {
+			return err
+		}
+
+		_, err := tx.CreateBucketIfNotExists(diskStorageQueueBucket)
+
+		return err
+	})
+	if err != nil {
+		db.Close()
+		os.Remove(f.Name())
+
+		return nil, errors.Wrap(err, "error initializing the storage database")
+	}
+
+	return &DiskStorage{db: db}, nil
+}
+
+func (s *DiskStorage) Add(key string) (bool, error) {
+	added := false
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(diskStorageBucket)
+		if b.Get([]byte(key)) != nil {
+			return nil
+		}
+
+		added = true
+
+		return b.Put([]byte(key), []byte{1})
+	})
+
+	return added, err
+}
+
+func (s *DiskStorage) Keys(prefix string) ([]string, error) {
+	keys := []string{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(diskStorageBucket).Cursor()
+
+		for k, _ := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, _ = c.Next() {
+			keys = append(keys, string(k))
+		}
+
+		return nil
+	})
+
+	return keys, err
+}
+
+func (s *DiskStorage) Push(u string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(diskStorageQueueBucket)
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		// The keys are ordered as pushed.
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+
+		return b.Put(key, []byte(u))
+	})
+}
+
+func (s *DiskStorage) Pop() (string, bool, error) {
+	var (
+		u     string
+		found bool
+	)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(diskStorageQueueBucket).Cursor()
+
+		k, v := c.First()
+		if k == nil {
+			return nil
+		}
+
+		u, found = string(v), true
+
+		return c.Delete()
+	})
+
+	return u, found, err
+}
+
+func (s *DiskStorage) Queued() ([]string, error) {
+	queued := []string{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(diskStorageQueueBucket).ForEach(func(_, v []byte) error {
+			queued = append(queued, string(v))
+
+			return nil
+		})
+	})
+
+	return queued, err
+}
+
+func (s *DiskStorage) Close() error {
+	path := s.db.Path()
+
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// BloomStorage is a Storage keeping the keys in a bloom filter, whose memory is fixed by
+// its capacity and false positive rate. Keys are reported as already present with the false
+// positive rate, so that the URLs and the files of those keys are missing from the Result.
+type BloomStorage struct {
+	mu     sync.Mutex
+	filter *bloom.BloomFilter
+}
+
+// NewBloomStorage returns an empty BloomStorage for the number of keys capacity,
+// with the false positive rate fpRate.
+func NewBloomStorage(capacity uint, fpRate float64) *BloomStorage {
+	return &BloomStorage{filter: bloom.NewWithEstimates(capacity, fpRate)}
+}
+
+func (s *BloomStorage) Add(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return !s.filter.TestAndAddString(key), nil
+}
+
+func (s *BloomStorage) Close() error {
+	return nil
+}
+
+// storageSet is the set of the keys with prefix in a Storage.
+type storageSet struct {
+	storage Storage
+	prefix  string
+}
+
+// add adds the key to the set and returns whether it was not already present.
+// If the storage fails, the key is considered as not present.
+func (s *storageSet) add(key string) bool {
+	added, err := s.storage.Add(s.prefix + key)
+	if err != nil {
+		log.Printf("error: storage: %v\n", err)
+
+		return true
+	}
+
+	return added
+}
+
+// list returns the keys of the set, sorted, if the storage lists its keys.
+func (s *storageSet) list() []string {
+	lister, ok := s.storage.(StorageLister)
+	if !ok {
+		return nil
+	}
+
+	keys, err := lister.Keys(s.prefix)
+	if err != nil {
+		log.Printf("error: storage: %v\n", err)
+
+		return nil
+	}
+
+	for k, v := range keys {
+		keys[k] = strings.TrimPrefix(v, s.prefix)
+	}
+
+	return keys
+}
+
+// collyStorage is the storage of a collector keeping its visited requests in a Storage.
+// Cookies are managed by the transport of the Find job.
+type collyStorage struct {
+	storage Storage
+}
+
+var _ storage.Storage = &collyStorage{}
+
+func (s *collyStorage) Init() error {
+	return nil
+}
+
+func (s *collyStorage) Visited(uint64) error {
+	return nil
+}
+
+// IsVisited marks the request as visited, as it's called by the collector before visiting it.
+func (s *collyStorage) IsVisited(requestID uint64) (bool, error) {
+	added, err := s.storage.Add(collyKeyPrefix + strconv.FormatUint(requestID, 16))
+
+	return !added, err
+}
+
+func (s *collyStorage) Cookies(*url.URL) string {
+	return ""
+}
+
+func (s *collyStorage) SetCookies(*url.URL, string) {}
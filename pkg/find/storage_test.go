@@ -0,0 +1,199 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maxgio92/wfind/pkg/find"
+)
+
+// storages returns the storages to test, by name.
+func storages(t testing.TB) map[string]func() find.Storage {
+	return map[string]func() find.Storage{
+		"memory": func() find.Storage {
+			return find.NewMemoryStorage()
+		},
+		"disk": func() find.Storage {
+			s, err := find.NewDiskStorage(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return s
+		},
+		"bloom": func() find.Storage {
+			return find.NewBloomStorage(100000, find.DefaultBloomFPRate)
+		},
+	}
+}
+
+// newSyntheticTree returns a server of a tree of listings below listingPath, with depth levels
+// of folders, each with folders subfolders and files files.
+func newSyntheticTree(depth, folders, files int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		level := strings.Count(strings.TrimPrefix(r.URL.Path, listingPath), "/")
+
+		w.Header().Set("Content-Type", "text/html")
+
+		if level < depth {
+			for i := 0; i < folders; i++ {
+				fmt.Fprintf(w, "<a href=\"dir-%d/\">dir-%d/</a>\n", i, i)
+			}
+		}
+
+		for i := 0; i < files; i++ {
+			fmt.Fprintf(w, "<a href=\"file-%d-%d.tar.gz\">file-%d-%d.tar.gz</a>\n", level, i, level, i)
+		}
+	}))
+}
+
+func TestStorage(t *testing.T) {
+	t.Parallel()
+
+	for name, newStorage := range storages(t) {
+		s := newStorage()
+
+		for _, tc := range []struct {
+			key   string
+			added bool
+		}{
+			{"visited:a", true},
+			{"visited:b", true},
+			{"visited:a", false},
+			{"result:a", true},
+		} {
+			added, err := s.Add(tc.key)
+
+			assert.Nil(t, err, name)
+			assert.Equal(t, tc.added, added, name, tc.key)
+		}
+
+		if lister, ok := s.(find.StorageLister); ok {
+			keys, err := lister.Keys("visited:")
+
+			assert.Nil(t, err, name)
+			assert.Equal(t, []string{"visited:a", "visited:b"}, keys, name)
+		}
+
+		if queue, ok := s.(find.QueueStorage); ok {
+			assert.Nil(t, queue.Push("b"), name)
+			assert.Nil(t, queue.Push("a"), name)
+
+			queued, err := queue.Queued()
+
+			assert.Nil(t, err, name)
+			assert.Equal(t, []string{"b", "a"}, queued, name)
+
+			for _, v := range queued {
+				u, ok, err := queue.Pop()
+
+				assert.Nil(t, err, name)
+				assert.True(t, ok, name)
+				assert.Equal(t, v, u, name)
+			}
+
+			_, ok, err = queue.Pop()
+
+			assert.Nil(t, err, name)
+			assert.False(t, ok, name)
+		}
+
+		assert.Nil(t, s.Close(), name)
+	}
+}
+
+func TestFindFileStorage(t *testing.T) {
+	t.Parallel()
+
+	server := newSyntheticTree(2, 3, 4)
+	defer server.Close()
+
+	for name, newStorage := range storages(t) {
+		storage := newStorage()
+
+		found, err := find.NewFind(
+			find.WithSeedURLs([]string{server.URL + listingPath}),
+			find.WithFilenameRegexp(`.+\.tar\.gz`),
+			find.WithFileType(find.FileTypeReg),
+			find.WithRecursive(true),
+			find.WithAsync(true),
+			find.WithStorage(storage),
+		).Find()
+
+		assert.Nil(t, err, name)
+
+		// The files of each level are found once per folder of the level.
+		assert.Len(t, found.URLs, 4+3*4+3*3*4, name)
+		assert.Nil(t, storage.Close(), name)
+	}
+}
+
+func BenchmarkFindFileStorage(b *testing.B) {
+	server := newSyntheticTree(3, 10, 20)
+	defer server.Close()
+
+	for name, newStorage := range storages(b) {
+		newStorage := newStorage
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				storage := newStorage()
+
+				_, err := find.NewFind(
+					find.WithSeedURLs([]string{server.URL + listingPath}),
+					find.WithFilenameRegexp(`.+\.tar\.gz`),
+					find.WithFileType(find.FileTypeReg),
+					find.WithRecursive(true),
+					find.WithAsync(true),
+					find.WithStorage(storage),
+				).Find()
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				storage.Close()
+			}
+		})
+	}
+}
+
+func BenchmarkStorageAdd(b *testing.B) {
+	for name, newStorage := range storages(b) {
+		newStorage := newStorage
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			storage := newStorage()
+			defer storage.Close()
+
+			for i := 0; i < b.N; i++ {
+				//nolint:errcheck
+				storage.Add(fmt.Sprintf("visited:https://mirrors.kernel.org/pub/linux/dir-%d/", i))
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2023 maxgio92 me@maxgio.me
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package find
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// transport returns the HTTP transport of the Find job. Its layers, from the outermost to
+// the innermost, are:
+//
+//   - bodyTransport, limiting the response bodies;
+//   - cookieTransport, sending the Cookies to the seed URLs and storing the ones received;
+//   - snapshotTransport, if a Snapshot is set, skipping the subtrees not modified since it;
+//   - robotsTransport, if RespectRobots is set, skipping the requests disallowed by robots.txt;
+//   - limitTransport, limiting the concurrency, the rate and the delay of the requests;
+//   - breakerTransport, if CircuitBreakerThreshold is set, failing fast the requests to failing hosts;
+//   - ClientTransport, or http.DefaultTransport, making the requests.
+//
+// The Find job wraps it in turn with the transport of its budget, canceling the requests once
+// the budget is exhausted.
+func (o *Options) transport(seeds []*url.URL) http.RoundTripper {
+	jar := o.CookieJar
+	if jar == nil {
+		jar = NewCookieJar()
+	}
+
+	if len(o.Cookies) > 0 {
+		for _, seed := range seeds {
+			cookies := make([]*http.Cookie, 0, len(o.Cookies))
+
+			for _, v := range o.Cookies {
+				c := *v
+				if c.Path == "" {
+					c.Path = "/"
+				}
+
+				cookies = append(cookies, &c)
+			}
+
+			jar.SetCookies(seed, cookies)
+		}
+	}
+
+	next := o.ClientTransport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	// The circuit breaker is checked once the request can be made.
+	if o.CircuitBreakerThreshold > 0 {
+		next = &breakerTransport{
+			breaker: newBreaker(o.CircuitBreakerThreshold, o.CircuitBreakerCooldown, o.Verbose),
+			next:    next,
+		}
+	}
+
+	limiter := newLimiter(o.Parallelism, o.limitRules(), o.AdaptiveConcurrency, o.Verbose)
+
+	next = &limitTransport{limiter: limiter, next: next}
+
+	if o.RespectRobots {
+		next = newRobotsTransport(limiter, o.Verbose, next)
+	}
+
+	// The listings not modified since the snapshot are neither limited nor checked against robots.txt.
+	if o.Snapshot != nil {
+		next = newSnapshotTransport(o.Snapshot, next)
+	}
+
+	next = &cookieTransport{jar: jar, next: next}
+
+	// The bodies are limited by the Find job, to detect the truncated ones.
+	return &bodyTransport{
+		limit:       int64(o.MaxBodySize),
+		stream:      o.StreamListings,
+		streamLimit: o.MaxListingSize,
+		next:        next,
+	}
+}
@@ -16,7 +16,9 @@ limitations under the License.
 
 package find
 
-import "net/url"
+import (
+	"net/url"
+)
 
 func getHostnamesFromURLs(urls []*url.URL) []string {
 	hostnames := []string{}
@@ -28,9 +30,12 @@ func getHostnamesFromURLs(urls []*url.URL) []string {
 	return hostnames
 }
 
-func urlSliceContains(us []*url.URL, u *url.URL) bool {
+// urlSliceContains returns whether the URL u is equivalent to one of the URLs us.
+func (o *Options) urlSliceContains(us []*url.URL, u *url.URL) bool {
+	key := o.urlKey(u)
+
 	for _, v := range us {
-		if v == u {
+		if o.urlKey(v) == key {
 			return true
 		}
 	}